@@ -0,0 +1,560 @@
+package main
+
+import (
+	"REST-API/config"
+	"REST-API/db"
+	"REST-API/logging"
+	"REST-API/models"
+	"REST-API/utils"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// a subcommand of the binary, e.g. `events-api create-admin --email ...`
+type command struct {
+	Name    string
+	Summary string
+	Run     func(args []string) error
+}
+
+var commands = []command{
+	{Name: "serve", Summary: "run the HTTP server (the default)", Run: serve},
+	{Name: "migrate", Summary: "create missing tables and apply pending migrations", Run: migrate},
+	{Name: "create-admin", Summary: "create an admin account", Run: createAdmin},
+	{Name: "set-role", Summary: "change a user's role", Run: setRole},
+	{Name: "reset-password", Summary: "set a new password and sign the user out everywhere", Run: resetPassword},
+	{Name: "purge-expired-tokens", Summary: "delete refresh tokens past their expiry", Run: purgeExpiredTokens},
+	{Name: "seed", Summary: "create published sample events for local development", Run: seed},
+	{Name: "export", Summary: "write every event as CSV or NDJSON", Run: exportEvents},
+	{Name: "import", Summary: "create events from a CSV or iCalendar file", Run: importEvents},
+	{Name: "backup", Summary: "back up the database now, or list backups with --list", Run: backup},
+	{Name: "restore", Summary: "replace the database with a verified backup (stop the server first)", Run: restore},
+	{Name: "config", Summary: "`config print` shows the effective configuration", Run: configCommand},
+}
+
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].Name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: events-api <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-22s %s\n", cmd.Name, cmd.Summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Every command also takes --config and the setting flags, see `events-api <command> -h`.")
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: events-api %s [flags]\n\nFlags:\n", name)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// exits with status 2 on bad flags, which the flag package has already
+// reported along with the usage
+func parseArgs(fs *flag.FlagSet, args []string) {
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		os.Exit(2)
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "unexpected argument %q\n", fs.Arg(0))
+		fs.Usage()
+		os.Exit(2)
+	}
+}
+
+// parses a command's flags along with the config flags and loads the config.
+// Logs go to stderr so stdout is left for the command's output. The returned
+// context is cancelled by Ctrl+C.
+func setupWithoutDB(fs *flag.FlagSet, args []string) (context.Context, context.CancelFunc) {
+	configArgs := config.BindFlags(fs)
+	parseArgs(fs, args)
+
+	loadConfig(configArgs())
+	if err := logging.Setup(os.Stderr, config.Get().LogFormat, config.Get().LogLevel); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid logging configuration: %v\n", err)
+		os.Exit(2)
+	}
+
+	return signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+}
+
+// like setupWithoutDB, and also opens the database the same way serve does.
+// The returned function closes it.
+func setup(fs *flag.FlagSet, args []string) (context.Context, func()) {
+	ctx, stop := setupWithoutDB(fs, args)
+
+	db.InitDB()
+	utils.RegisterCustomValidations()
+
+	return ctx, func() {
+		stop()
+		if err := db.Close(); err != nil {
+			slog.Error("Error closing database", "error", err)
+		}
+	}
+}
+
+// fails with the usage message when a required flag is missing
+func requireFlag(fs *flag.FlagSet, name, value string) error {
+	if value == "" {
+		fs.Usage()
+		return fmt.Errorf("--%s is required", name)
+	}
+	return nil
+}
+
+// records an action taken from the command line. There's no signed-in actor,
+// so the request ID names the command instead.
+func recordCLIAudit(ctx context.Context, command, action, resourceType string, resourceID int, before, after any) {
+	entry := models.AuditEntry{
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		RequestID:    "cli:" + command,
+	}
+	if before != nil || after != nil {
+		changes, err := utils.Diff(before, after)
+		if err != nil {
+			slog.Error("could not diff audit entry", "action", action, "error", err)
+		}
+		entry.Changes = changes
+	}
+	if err := models.RecordAudit(ctx, &entry); err != nil {
+		slog.Error("could not record audit entry", "action", action, "error", err)
+	}
+}
+
+func getUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	user, err := models.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("%w: %s", models.ErrUserNotFound, email)
+	}
+	return user, nil
+}
+
+// checks a password against the same rules as signup, or generates one when
+// none was given. generated reports whether it has to be shown to the operator.
+func passwordOrGenerate(password string) (string, bool, error) {
+	if password == "" {
+		password, err := utils.GeneratePassword()
+		return password, true, err
+	}
+	if errs := utils.ValidateStructPartial(models.User{Password: password}, "Password"); errs != nil {
+		return "", false, errors.New(errs[0].Message)
+	}
+	return password, false, nil
+}
+
+func migrate(args []string) error {
+	fs := newFlagSet("migrate")
+	// setup opens the database, which applies pending migrations
+	ctx, done := setup(fs, args)
+	defer done()
+
+	version, err := db.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Schema is at version %d\n", version)
+	return nil
+}
+
+func createAdmin(args []string) error {
+	fs := newFlagSet("create-admin")
+	email := fs.String("email", "", "email of the new admin (required)")
+	password := fs.String("password", "", "password, a random one is generated and printed if omitted")
+	ctx, done := setup(fs, args)
+	defer done()
+
+	if err := requireFlag(fs, "email", *email); err != nil {
+		return err
+	}
+	pass, generated, err := passwordOrGenerate(*password)
+	if err != nil {
+		return err
+	}
+
+	user := models.User{Email: *email, Password: pass}
+	if errs := utils.ValidateStruct(user); errs != nil {
+		return errors.New(errs[0].Message)
+	}
+	if err := user.SaveWithRole(ctx, "admin"); err != nil {
+		if errors.Is(err, models.ErrEmailTaken) {
+			return fmt.Errorf("%w, use set-role to promote an existing user", err)
+		}
+		return err
+	}
+
+	recordCLIAudit(ctx, "create-admin", models.AuditUserCreate, "user", user.ID, nil, map[string]any{"email": user.Email, "role": "admin"})
+
+	fmt.Printf("Created admin %s (id %d)\n", user.Email, user.ID)
+	if generated {
+		fmt.Printf("Password: %s\n", pass)
+	}
+	return nil
+}
+
+func setRole(args []string) error {
+	fs := newFlagSet("set-role")
+	email := fs.String("email", "", "email of the user (required)")
+	role := fs.String("role", "", "new role: "+strings.Join(models.UserRoles, ", ")+" (required)")
+	ctx, done := setup(fs, args)
+	defer done()
+
+	if err := requireFlag(fs, "email", *email); err != nil {
+		return err
+	}
+	if !slices.Contains(models.UserRoles, *role) {
+		return fmt.Errorf("--role must be one of: %s", strings.Join(models.UserRoles, ", "))
+	}
+
+	user, err := getUserByEmail(ctx, *email)
+	if err != nil {
+		return err
+	}
+	previous, err := models.SetUserRole(ctx, user.ID, *role)
+	if err != nil {
+		return err
+	}
+
+	recordCLIAudit(ctx, "set-role", models.AuditUserRoleChange, "user", user.ID,
+		map[string]any{"role": previous}, map[string]any{"role": *role})
+
+	fmt.Printf("Changed role of %s from %s to %s\n", user.Email, previous, *role)
+	return nil
+}
+
+func resetPassword(args []string) error {
+	fs := newFlagSet("reset-password")
+	email := fs.String("email", "", "email of the user (required)")
+	password := fs.String("password", "", "new password, a random one is generated and printed if omitted")
+	ctx, done := setup(fs, args)
+	defer done()
+
+	if err := requireFlag(fs, "email", *email); err != nil {
+		return err
+	}
+	pass, generated, err := passwordOrGenerate(*password)
+	if err != nil {
+		return err
+	}
+
+	user, err := getUserByEmail(ctx, *email)
+	if err != nil {
+		return err
+	}
+	if err := models.SetUserPassword(ctx, user.ID, pass); err != nil {
+		return err
+	}
+
+	// the password itself is never recorded
+	recordCLIAudit(ctx, "reset-password", models.AuditUserPasswordSet, "user", user.ID, nil, nil)
+
+	fmt.Printf("Reset the password of %s and signed them out everywhere\n", user.Email)
+	if generated {
+		fmt.Printf("Password: %s\n", pass)
+	}
+	return nil
+}
+
+func purgeExpiredTokens(args []string) error {
+	fs := newFlagSet("purge-expired-tokens")
+	ctx, done := setup(fs, args)
+	defer done()
+
+	purged, err := models.PurgeExpiredRefreshTokens(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Purged %d expired refresh tokens\n", purged)
+	return nil
+}
+
+var seedLocations = []string{"Main Hall", "Room 101", "Rooftop Terrace", "Online", "Community Center"}
+
+func seed(args []string) error {
+	fs := newFlagSet("seed")
+	count := fs.Int("events", 10, "number of events to create")
+	ownerEmail := fs.String("owner", "seed@example.com", "email of the events' owner, created if missing")
+	ctx, done := setup(fs, args)
+	defer done()
+
+	if *count < 1 {
+		return errors.New("--events must be at least 1")
+	}
+
+	owner, err := models.GetUserByEmail(ctx, *ownerEmail)
+	if err != nil {
+		return err
+	}
+	if owner == nil {
+		password, err := utils.GeneratePassword()
+		if err != nil {
+			return err
+		}
+		owner = &models.User{Email: *ownerEmail, Password: password}
+		if err := owner.Save(ctx); err != nil {
+			return err
+		}
+		fmt.Printf("Created user %s with password %s\n", owner.Email, password)
+	}
+
+	// one event a day from tomorrow, each two hours long
+	start := time.Now().UTC().Truncate(24 * time.Hour).Add(24*time.Hour + 18*time.Hour)
+	events := make([]models.Event, *count)
+	for i := range events {
+		dateTime := start.Add(time.Duration(i) * 24 * time.Hour)
+		endDateTime := dateTime.Add(2 * time.Hour)
+		events[i] = models.Event{
+			Name:        fmt.Sprintf("Sample event %d", i+1),
+			Description: "Generated by the seed command for local development.",
+			Location:    seedLocations[i%len(seedLocations)],
+			DateTime:    dateTime,
+			EndDateTime: &endDateTime,
+			UserID:      owner.ID,
+			Status:      models.EventStatusPublished,
+		}
+	}
+
+	if err := models.SaveEvents(ctx, events); err != nil {
+		return err
+	}
+	fmt.Printf("Created %d events owned by %s\n", len(events), owner.Email)
+	return nil
+}
+
+// the import CSV columns come first, so an export can be imported again
+var eventExportHeader = []string{"name", "description", "location", "dateTime", "endDateTime", "id", "status", "userId"}
+
+func exportEvents(args []string) error {
+	fs := newFlagSet("export")
+	format := fs.String("format", "csv", "csv or ndjson")
+	out := fs.String("out", "", "file to write, stdout if omitted")
+	ctx, done := setup(fs, args)
+	defer done()
+
+	if *format != "csv" && *format != "ndjson" {
+		return errors.New("--format must be csv or ndjson")
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	written := 0
+	var err error
+	if *format == "ndjson" {
+		encoder := json.NewEncoder(w)
+		err = models.ForEachEvent(ctx, func(event models.Event) error {
+			written++
+			return encoder.Encode(event)
+		})
+	} else {
+		writer := csv.NewWriter(w)
+		writer.Write(eventExportHeader)
+		err = models.ForEachEvent(ctx, func(event models.Event) error {
+			written++
+			endDateTime := ""
+			if event.EndDateTime != nil {
+				endDateTime = event.EndDateTime.UTC().Format(time.RFC3339)
+			}
+			return writer.Write([]string{
+				event.Name,
+				event.Description,
+				event.Location,
+				event.DateTime.UTC().Format(time.RFC3339),
+				endDateTime,
+				strconv.Itoa(event.ID),
+				event.Status,
+				strconv.Itoa(event.UserID),
+			})
+		})
+		writer.Flush()
+		if err == nil {
+			err = writer.Error()
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// stdout may be the export itself, so the summary goes to stderr
+	fmt.Fprintf(os.Stderr, "Exported %d events\n", written)
+	return nil
+}
+
+func importEvents(args []string) error {
+	fs := newFlagSet("import")
+	path := fs.String("file", "", "CSV or iCalendar file to import (required)")
+	format := fs.String("format", "", "csv or ics, taken from the file extension if omitted")
+	ownerEmail := fs.String("owner", "", "email of the user who will own the events (required)")
+	dryRun := fs.Bool("dry-run", false, "validate the file without creating anything")
+	ctx, done := setup(fs, args)
+	defer done()
+
+	if err := requireFlag(fs, "file", *path); err != nil {
+		return err
+	}
+	if err := requireFlag(fs, "owner", *ownerEmail); err != nil {
+		return err
+	}
+
+	owner, err := getUserByEmail(ctx, *ownerEmail)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(*path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	events, rowErrors, err := models.ParseImport(file, *path, *format, owner.ID)
+	if err != nil {
+		return err
+	}
+	if len(rowErrors) > 0 {
+		for _, rowError := range rowErrors {
+			for _, fieldError := range rowError.Errors {
+				fmt.Fprintf(os.Stderr, "row %d: %s\n", rowError.Row, fieldError.Message)
+			}
+		}
+		return errors.New("validation failed, no events were imported")
+	}
+
+	if *dryRun {
+		fmt.Printf("Dry run successful, %d events are valid and none were imported\n", len(events))
+		return nil
+	}
+
+	if err := models.SaveEvents(ctx, events); err != nil {
+		return err
+	}
+	for _, event := range events {
+		recordCLIAudit(ctx, "import", models.AuditEventCreate, "event", event.ID, nil, event)
+	}
+
+	fmt.Printf("Imported %d events owned by %s\n", len(events), owner.Email)
+	return nil
+}
+
+func backup(args []string) error {
+	fs := newFlagSet("backup")
+	list := fs.Bool("list", false, "list existing backups instead of taking one")
+	ctx, done := setup(fs, args)
+	defer done()
+
+	if *list {
+		backups, err := db.ListBackups(config.Get().BackupDir)
+		if err != nil {
+			return err
+		}
+		for _, backup := range backups {
+			fmt.Printf("%s\t%s\t%d bytes\n", backup.Name, backup.CreatedAt.Format(time.RFC3339), backup.Size)
+		}
+		return nil
+	}
+
+	info, _, err := db.BackupAndPrune(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s (%d bytes)\n", info.Path, info.Size)
+	return nil
+}
+
+func restore(args []string) error {
+	fs := newFlagSet("restore")
+	name := fs.String("backup", "", "file name of the backup to restore, see `backup --list`")
+	at := fs.String("at", "", "restore the newest backup taken at or before this RFC 3339 time instead")
+	// the database must not be open while its file is swapped
+	ctx, stop := setupWithoutDB(fs, args)
+	defer stop()
+
+	cfg := config.Get()
+	var info *db.BackupInfo
+	var err error
+	switch {
+	case *name != "" && *at != "":
+		return errors.New("pass either --backup or --at, not both")
+	case *name != "":
+		info, err = db.FindBackup(cfg.BackupDir, *name)
+	case *at != "":
+		pointInTime, parseErr := time.Parse(time.RFC3339, *at)
+		if parseErr != nil {
+			return errors.New("--at must be an RFC 3339 time, like 2024-05-01T12:00:00Z")
+		}
+		info, err = db.FindBackupAt(cfg.BackupDir, pointInTime)
+	default:
+		fs.Usage()
+		return errors.New("--backup or --at is required")
+	}
+	if err != nil {
+		return err
+	}
+
+	aside, err := db.RestoreBackup(ctx, info.Path, cfg.DBPath)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Restored %s from %s\n", cfg.DBPath, info.Name)
+	if aside != "" {
+		fmt.Printf("The previous database was moved to %s\n", aside)
+	}
+	return nil
+}
+
+// `config print` writes the effective configuration as YAML, secrets redacted
+func configCommand(args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "Usage: events-api config print [flags]")
+		os.Exit(2)
+	}
+
+	fs := newFlagSet("config print")
+	configArgs := config.BindFlags(fs)
+	parseArgs(fs, args[1:])
+	loadConfig(configArgs())
+
+	return config.Print(os.Stdout, config.Get())
+}
@@ -1,8 +1,13 @@
 package config
 
 import (
-	"log"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -11,49 +16,215 @@ import (
 type Config struct {
 	Port               string
 	DBPath             string
+	DBMaxOpenConns     int // read connections, writes always share one
+	DBMaxIdleConns     int
+	DBJournalMode      string
+	DBSynchronous      string
+	DBBusyTimeout      time.Duration // how long a connection waits for a lock held by another process
 	JWTSecret          string
 	AccessTokenExpiry  time.Duration
 	RefreshTokenExpiry time.Duration
 	RequestTimeout     time.Duration
+	ExportTimeout      time.Duration // replaces RequestTimeout for bulk exports and imports
+	ShutdownTimeout    time.Duration // how long in-flight requests get to finish on shutdown
+	DrainDelay         time.Duration // how long /readyz fails before shutdown starts on SIGTERM
+	DefaultPageLimit   int
+	MaxPageLimit       int
+	DeletedRetention   time.Duration // how long soft-deleted rows are kept before purging
+	PurgeInterval      time.Duration
+	IdempotencyTTL     time.Duration // how long responses are kept for Idempotency-Key replays
+	BackupDir          string
+	BackupInterval     time.Duration // 0 disables scheduled backups
+	BackupKeep         int           // newest backups kept when pruning
+	BackupMaxAge       time.Duration // 0 keeps backups regardless of age
+	OutboxWorkers      int
+	OutboxPollInterval time.Duration
+	OutboxLease        time.Duration // how long a handler gets before its message is delivered again
+	OutboxMaxAttempts  int
+	OutboxBackoffBase  time.Duration
+	OutboxBackoffMax   time.Duration
+	OutboxRetention    time.Duration // how long delivered messages are kept
+	WebhookTimeout     time.Duration // per delivery attempt
+	WebhookAllowLocal  bool          // lets webhooks reach loopback and private addresses
+	WebhookRetention   time.Duration // how long delivery log entries are kept
+	StreamHeartbeat    time.Duration // how often idle event streams send a comment
+	JobCompleteEvents  string        // cron spec, see scheduler.Parse
+	JobPurgeTokens     string        // cron spec
+	LogFormat          string        // json, text or pretty
+	LogLevel           string        // debug, info, warn or error
+	TraceExporter      string        // none, otlp, stdout or file
+	TraceFile          string
+	TraceSampleRatio   float64
 }
 
-var App Config
+var current atomic.Pointer[Config]
 
-func Load() {
-	err := godotenv.Load()
+// returns the active configuration. Reload may swap it at any time, so
+// callers that need several settings to agree should read it once.
+func Get() *Config {
+	return current.Load()
+}
+
+// what Reload needs to read the same sources again
+var (
+	loadMu   sync.Mutex
+	loadArgs []string
+)
+
+// loads the configuration from, in increasing priority: defaults, the file
+// named by --config or CONFIG_FILE (YAML or TOML), a .env file in the
+// working directory, environment variables and command line flags. Every
+// invalid setting is reported in the returned error, not just the first.
+func Load(args []string) error {
+	loadMu.Lock()
+	defer loadMu.Unlock()
+
+	cfg, err := build(args)
+	if err != nil {
+		return err
+	}
+
+	loadArgs = args
+	current.Store(cfg)
+	return nil
+}
+
+// re-reads every source and applies the settings that are safe to change
+// while running. Changes to any other setting are returned in ignored and
+// only take effect after a restart. An invalid configuration is rejected
+// as a whole and the active one is kept.
+func Reload() (changed, ignored []string, err error) {
+	loadMu.Lock()
+	defer loadMu.Unlock()
+
+	next, err := build(loadArgs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	active := *current.Load()
+	for _, s := range settings {
+		if s.format(&active) == s.format(next) {
+			continue
+		}
+		if !s.Reloadable {
+			ignored = append(ignored, s.Key)
+			continue
+		}
+		s.copy(&active, next)
+		changed = append(changed, s.Key)
+	}
+
+	current.Store(&active)
+	return changed, ignored, nil
+}
+
+func build(args []string) (*Config, error) {
+	path, flags, err := parseFlags(args)
 	if err != nil {
-		log.Println("No .env file found, reading from environment variables directly")
+		return nil, err
 	}
 
-	App = Config{
-		Port:               getEnv("PORT", "8080"),
-		DBPath:             getEnv("DB_PATH", "api.db"),
-		JWTSecret:          getEnv("JWT_SECRET", ""),
-		AccessTokenExpiry:  parseDuration("ACCESS_TOKEN_EXPIRY", "15m"),
-		RefreshTokenExpiry: parseDuration("REFRESH_TOKEN_EXPIRY", "168h"),
-		RequestTimeout:     parseDuration("REQUEST_TIMEOUT", "30s"),
+	// read on every build rather than copied into the environment once, so
+	// edits to it are picked up by Reload
+	dotenv, err := godotenv.Read()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf(".env: %w", err)
+	}
+	getenv := func(name string) (string, string) {
+		if value := os.Getenv(name); value != "" {
+			return value, "env " + name
+		}
+		return dotenv[name], ".env " + name
+	}
+
+	if path == "" {
+		path, _ = getenv("CONFIG_FILE")
+	}
+	var file map[string]string
+	if path != "" {
+		if file, err = readFile(path); err != nil {
+			return nil, err
+		}
 	}
 
-	if App.JWTSecret == "" {
-		log.Fatal("JWT_SECRET environment variable is required")
+	var errs []error
+	for key := range file {
+		if lookup(key) == nil {
+			errs = append(errs, fmt.Errorf("%s: unknown setting %q", path, key))
+		}
 	}
+
+	cfg := &Config{}
+	for _, s := range settings {
+		raw, source := s.Default, "default"
+		if value, ok := file[s.Key]; ok {
+			raw, source = value, path
+		}
+		if value, from := getenv(s.Env); value != "" {
+			raw, source = value, from
+		}
+		if value, ok := flags[s.Key]; ok {
+			raw, source = value, "flag --"+s.Key
+		}
+
+		if err := s.parse(cfg, raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s (from %s): %w", s.Key, source, err))
+		}
+	}
+
+	// range checks only make sense once every value has parsed
+	if len(errs) == 0 {
+		errs = cfg.validate()
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return cfg, nil
+}
+
+// reads --config and one flag per setting, named after its key. Only the
+// flags that were actually passed are returned.
+func parseFlags(args []string) (path string, flags map[string]string, err error) {
+	fs := flag.NewFlagSet("events-api", flag.ContinueOnError)
+	defineFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return "", nil, err
+	}
+	if fs.NArg() > 0 {
+		return "", nil, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	flags = make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "config" {
+			path = f.Value.String()
+		} else {
+			flags[f.Name] = f.Value.String()
+		}
+	})
+	return path, flags, nil
 }
 
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+func defineFlags(fs *flag.FlagSet) {
+	fs.String("config", "", "path to a YAML or TOML config file (or CONFIG_FILE)")
+	for _, s := range settings {
+		fs.String(s.Key, "", fmt.Sprintf("overrides %s (default %q)", s.Env, s.Default))
 	}
-	return value
 }
 
-// helper to parse duration strings like "15m" or "168h"
-func parseDuration(key, defaultValue string) time.Duration {
-	value := getEnv(key, defaultValue)
-	duration, err := time.ParseDuration(value)
-	if err != nil {
-		log.Printf("Invalid duration for %s, using default %s", key, defaultValue)
-		duration, _ = time.ParseDuration(defaultValue)
+// adds --config and the setting flags to a command's own flag set. Once fs
+// is parsed, the returned function gives the config flags that were passed,
+// ready for Load.
+func BindFlags(fs *flag.FlagSet) func() []string {
+	defineFlags(fs)
+	return func() []string {
+		var args []string
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name == "config" || lookup(f.Name) != nil {
+				args = append(args, "--"+f.Name+"="+f.Value.String())
+			}
+		})
+		return args
 	}
-	return duration
 }
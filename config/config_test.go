@@ -0,0 +1,213 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("could not write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+server:
+  port: 9000
+  request_timeout: 45s
+database:
+  path: from-file.db
+auth:
+  jwt_secret: file-secret
+`)
+	t.Setenv("REQUEST_TIMEOUT", "20s")
+	t.Setenv("DB_PATH", "from-env.db")
+
+	err := Load([]string{"--config", path, "--database.path", "from-flag.db"})
+	if err != nil {
+		t.Fatalf("expected config to load, got: %v", err)
+	}
+
+	cfg := Get()
+	if cfg.Port != "9000" {
+		t.Errorf("expected the file to override the default port, got %q", cfg.Port)
+	}
+	if cfg.RequestTimeout != 20*time.Second {
+		t.Errorf("expected env to override the file, got %v", cfg.RequestTimeout)
+	}
+	if cfg.DBPath != "from-flag.db" {
+		t.Errorf("expected the flag to override env, got %q", cfg.DBPath)
+	}
+	if cfg.MaxPageLimit != 100 {
+		t.Errorf("expected the default max page limit, got %d", cfg.MaxPageLimit)
+	}
+}
+
+func TestBindFlags(t *testing.T) {
+	t.Setenv("JWT_SECRET", "secret")
+
+	fs := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	email := fs.String("email", "", "")
+	configArgs := BindFlags(fs)
+	if err := fs.Parse([]string{"--email", "a@example.com", "--log.level", "debug"}); err != nil {
+		t.Fatalf("expected flags to parse, got: %v", err)
+	}
+
+	if *email != "a@example.com" {
+		t.Errorf("expected the command's own flag to be set, got %q", *email)
+	}
+	if err := Load(configArgs()); err != nil {
+		t.Fatalf("expected config to load, got: %v", err)
+	}
+	if Get().LogLevel != "debug" {
+		t.Errorf("expected the config flag to be passed on, got %q", Get().LogLevel)
+	}
+}
+
+func TestLoad_TOML(t *testing.T) {
+	path := writeConfigFile(t, "config.toml", `
+[auth]
+jwt_secret = "toml-secret"
+
+[pagination]
+default_limit = 25
+`)
+
+	if err := Load([]string{"--config", path}); err != nil {
+		t.Fatalf("expected config to load, got: %v", err)
+	}
+	if Get().JWTSecret != "toml-secret" || Get().DefaultPageLimit != 25 {
+		t.Errorf("expected values from the TOML file, got %+v", Get())
+	}
+}
+
+func TestLoad_ReportsEveryError(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+server:
+  request_timeout: soon
+  prot: 8080
+`)
+	t.Setenv("DB_MAX_OPEN_CONNS", "many")
+	t.Setenv("JWT_SECRET", "")
+
+	err := Load([]string{"--config", path})
+	if err == nil {
+		t.Fatal("expected invalid settings to be rejected")
+	}
+	for _, want := range []string{"server.request_timeout", "server.prot", "database.max_open_conns", "env DB_MAX_OPEN_CONNS"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected the error to mention %q, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoad_ValidatesRanges(t *testing.T) {
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("PAGE_DEFAULT_LIMIT", "500")
+	t.Setenv("TRACE_SAMPLE_RATIO", "2")
+
+	err := Load(nil)
+	if err == nil {
+		t.Fatal("expected out of range settings to be rejected")
+	}
+	for _, want := range []string{"auth.jwt_secret", "pagination.default_limit", "tracing.sample_ratio"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected the error to mention %q, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestReload_RereadsDotEnv(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("LOG_LEVEL", "")
+	if err := os.WriteFile(".env", []byte("LOG_LEVEL=info\n"), 0o600); err != nil {
+		t.Fatalf("could not write .env: %v", err)
+	}
+	if err := Load(nil); err != nil {
+		t.Fatalf("expected config to load, got: %v", err)
+	}
+
+	if err := os.WriteFile(".env", []byte("LOG_LEVEL=debug\n"), 0o600); err != nil {
+		t.Fatalf("could not write .env: %v", err)
+	}
+	changed, _, err := Reload()
+	if err != nil {
+		t.Fatalf("expected reload to succeed, got: %v", err)
+	}
+	if !slices.Equal(changed, []string{"log.level"}) || Get().LogLevel != "debug" {
+		t.Errorf("expected the edited .env to be applied, changed %v, level %q", changed, Get().LogLevel)
+	}
+
+	// real environment variables still win over .env
+	t.Setenv("LOG_LEVEL", "warn")
+	Reload()
+	if Get().LogLevel != "warn" {
+		t.Errorf("expected the environment to override .env, got %q", Get().LogLevel)
+	}
+}
+
+func TestReload_AppliesOnlyReloadableSettings(t *testing.T) {
+	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("LOG_LEVEL", "info")
+	t.Setenv("PORT", "8080")
+	if err := Load(nil); err != nil {
+		t.Fatalf("expected config to load, got: %v", err)
+	}
+
+	t.Setenv("LOG_LEVEL", "debug")
+	t.Setenv("PORT", "9090")
+	changed, ignored, err := Reload()
+	if err != nil {
+		t.Fatalf("expected reload to succeed, got: %v", err)
+	}
+
+	if !slices.Equal(changed, []string{"log.level"}) || !slices.Equal(ignored, []string{"server.port"}) {
+		t.Errorf("unexpected reload result, changed %v, ignored %v", changed, ignored)
+	}
+	if Get().LogLevel != "debug" || Get().Port != "8080" {
+		t.Errorf("expected only the log level to change, got level %q port %q", Get().LogLevel, Get().Port)
+	}
+
+	t.Setenv("LOG_LEVEL", "loud")
+	if _, _, err := Reload(); err == nil {
+		t.Error("expected an invalid reload to be rejected")
+	}
+	if Get().LogLevel != "debug" {
+		t.Errorf("expected a rejected reload to keep the active config, got %q", Get().LogLevel)
+	}
+}
+
+func TestPrint_RedactsSecrets(t *testing.T) {
+	t.Setenv("JWT_SECRET", "super-secret-value")
+	if err := Load(nil); err != nil {
+		t.Fatalf("expected config to load, got: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := Print(&out, Get()); err != nil {
+		t.Fatalf("expected print to succeed, got: %v", err)
+	}
+	if strings.Contains(out.String(), "super-secret-value") {
+		t.Error("expected the JWT secret to be redacted")
+	}
+	if !strings.Contains(out.String(), `retention:`) || !strings.Contains(out.String(), `deleted: "720h"`) {
+		t.Errorf("expected sections and readable durations, got:\n%s", out.String())
+	}
+
+	// the printed file must load back
+	path := writeConfigFile(t, "printed.yaml", out.String())
+	if _, err := readFile(path); err != nil {
+		t.Errorf("expected printed config to parse, got: %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// reads a YAML or TOML config file, chosen by extension, into a map of
+// dotted setting keys to raw values
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read config file: %w", err)
+	}
+
+	var document map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &document)
+	case ".toml":
+		err = toml.Unmarshal(data, &document)
+	default:
+		return nil, fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	flatten("", document, values)
+	return values, nil
+}
+
+// turns nested sections into dotted keys, e.g. server: {port: 8080}
+// becomes server.port = "8080"
+func flatten(prefix string, section map[string]any, values map[string]string) {
+	for key, value := range section {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]any); ok {
+			flatten(key, nested, values)
+			continue
+		}
+		values[key] = fmt.Sprint(value)
+	}
+}
+
+const redacted = "[REDACTED]"
+
+// writes cfg as a YAML config file, with secrets redacted
+func Print(w io.Writer, cfg *Config) error {
+	section := ""
+	for _, s := range settings {
+		name, key, _ := strings.Cut(s.Key, ".")
+		if name != section {
+			if section != "" {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "%s:\n", name)
+			section = name
+		}
+
+		value := strconv.Quote(s.format(cfg))
+		if s.Secret {
+			value = strconv.Quote(redacted)
+		}
+		comment := "# " + s.Env
+		if s.Reloadable {
+			comment += ", reloads on SIGHUP"
+		}
+		if _, err := fmt.Fprintf(w, "  %s: %s %s\n", key, value, comment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
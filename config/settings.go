@@ -0,0 +1,223 @@
+package config
+
+import (
+	"REST-API/scheduler"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// one configuration value. Key is its name in config files (sections
+// separated by dots) and as a command line flag, Env its environment
+// variable. Reloadable settings may change on SIGHUP without a restart.
+type setting struct {
+	Key        string
+	Env        string
+	Default    string
+	Secret     bool
+	Reloadable bool
+	field      func(*Config) any // pointer to the Config field holding the value
+}
+
+var settings = []setting{
+	{Key: "server.port", Env: "PORT", Default: "8080", field: func(c *Config) any { return &c.Port }},
+	{Key: "server.request_timeout", Env: "REQUEST_TIMEOUT", Default: "30s", field: func(c *Config) any { return &c.RequestTimeout }},
+	{Key: "server.export_timeout", Env: "EXPORT_TIMEOUT", Default: "5m", field: func(c *Config) any { return &c.ExportTimeout }},
+	{Key: "server.shutdown_timeout", Env: "SHUTDOWN_TIMEOUT", Default: "10s", Reloadable: true, field: func(c *Config) any { return &c.ShutdownTimeout }},
+	{Key: "server.drain_delay", Env: "DRAIN_DELAY", Default: "5s", Reloadable: true, field: func(c *Config) any { return &c.DrainDelay }},
+
+	{Key: "database.path", Env: "DB_PATH", Default: "api.db", field: func(c *Config) any { return &c.DBPath }},
+	{Key: "database.max_open_conns", Env: "DB_MAX_OPEN_CONNS", Default: "10", field: func(c *Config) any { return &c.DBMaxOpenConns }},
+	{Key: "database.max_idle_conns", Env: "DB_MAX_IDLE_CONNS", Default: "5", field: func(c *Config) any { return &c.DBMaxIdleConns }},
+	{Key: "database.journal_mode", Env: "DB_JOURNAL_MODE", Default: "wal", field: func(c *Config) any { return &c.DBJournalMode }},
+	{Key: "database.synchronous", Env: "DB_SYNCHRONOUS", Default: "normal", field: func(c *Config) any { return &c.DBSynchronous }},
+	{Key: "database.busy_timeout", Env: "DB_BUSY_TIMEOUT", Default: "5s", field: func(c *Config) any { return &c.DBBusyTimeout }},
+
+	{Key: "auth.jwt_secret", Env: "JWT_SECRET", Secret: true, field: func(c *Config) any { return &c.JWTSecret }},
+	{Key: "auth.access_token_expiry", Env: "ACCESS_TOKEN_EXPIRY", Default: "15m", field: func(c *Config) any { return &c.AccessTokenExpiry }},
+	{Key: "auth.refresh_token_expiry", Env: "REFRESH_TOKEN_EXPIRY", Default: "168h", field: func(c *Config) any { return &c.RefreshTokenExpiry }},
+
+	{Key: "pagination.default_limit", Env: "PAGE_DEFAULT_LIMIT", Default: "10", Reloadable: true, field: func(c *Config) any { return &c.DefaultPageLimit }},
+	{Key: "pagination.max_limit", Env: "PAGE_MAX_LIMIT", Default: "100", Reloadable: true, field: func(c *Config) any { return &c.MaxPageLimit }},
+
+	{Key: "retention.deleted", Env: "DELETED_RETENTION", Default: "720h", Reloadable: true, field: func(c *Config) any { return &c.DeletedRetention }},
+	{Key: "retention.purge_interval", Env: "PURGE_INTERVAL", Default: "1h", field: func(c *Config) any { return &c.PurgeInterval }},
+	{Key: "idempotency.ttl", Env: "IDEMPOTENCY_TTL", Default: "24h", field: func(c *Config) any { return &c.IdempotencyTTL }},
+
+	{Key: "backup.dir", Env: "BACKUP_DIR", Default: "backups", field: func(c *Config) any { return &c.BackupDir }},
+	{Key: "backup.interval", Env: "BACKUP_INTERVAL", Default: "24h", Reloadable: true, field: func(c *Config) any { return &c.BackupInterval }},
+	{Key: "backup.keep", Env: "BACKUP_KEEP", Default: "7", Reloadable: true, field: func(c *Config) any { return &c.BackupKeep }},
+	{Key: "backup.max_age", Env: "BACKUP_MAX_AGE", Default: "0s", Reloadable: true, field: func(c *Config) any { return &c.BackupMaxAge }},
+
+	{Key: "outbox.workers", Env: "OUTBOX_WORKERS", Default: "4", field: func(c *Config) any { return &c.OutboxWorkers }},
+	{Key: "outbox.poll_interval", Env: "OUTBOX_POLL_INTERVAL", Default: "1s", field: func(c *Config) any { return &c.OutboxPollInterval }},
+	{Key: "outbox.lease", Env: "OUTBOX_LEASE", Default: "5m", field: func(c *Config) any { return &c.OutboxLease }},
+	{Key: "outbox.max_attempts", Env: "OUTBOX_MAX_ATTEMPTS", Default: "10", field: func(c *Config) any { return &c.OutboxMaxAttempts }},
+	{Key: "outbox.backoff_base", Env: "OUTBOX_BACKOFF_BASE", Default: "5s", field: func(c *Config) any { return &c.OutboxBackoffBase }},
+	{Key: "outbox.backoff_max", Env: "OUTBOX_BACKOFF_MAX", Default: "1h", field: func(c *Config) any { return &c.OutboxBackoffMax }},
+	{Key: "outbox.retention", Env: "OUTBOX_RETENTION", Default: "168h", Reloadable: true, field: func(c *Config) any { return &c.OutboxRetention }},
+	{Key: "webhooks.timeout", Env: "WEBHOOK_TIMEOUT", Default: "10s", field: func(c *Config) any { return &c.WebhookTimeout }},
+	{Key: "webhooks.allow_private_targets", Env: "WEBHOOK_ALLOW_PRIVATE_TARGETS", Default: "false", field: func(c *Config) any { return &c.WebhookAllowLocal }},
+	{Key: "webhooks.delivery_retention", Env: "WEBHOOK_DELIVERY_RETENTION", Default: "720h", Reloadable: true, field: func(c *Config) any { return &c.WebhookRetention }},
+	{Key: "stream.heartbeat", Env: "STREAM_HEARTBEAT", Default: "15s", Reloadable: true, field: func(c *Config) any { return &c.StreamHeartbeat }},
+
+	{Key: "jobs.complete_events", Env: "JOB_COMPLETE_EVENTS", Default: "* * * * *", field: func(c *Config) any { return &c.JobCompleteEvents }},
+	{Key: "jobs.purge_tokens", Env: "JOB_PURGE_TOKENS", Default: "@hourly", field: func(c *Config) any { return &c.JobPurgeTokens }},
+
+	{Key: "log.format", Env: "LOG_FORMAT", Default: "json", field: func(c *Config) any { return &c.LogFormat }},
+	{Key: "log.level", Env: "LOG_LEVEL", Default: "info", Reloadable: true, field: func(c *Config) any { return &c.LogLevel }},
+
+	{Key: "tracing.exporter", Env: "TRACE_EXPORTER", Default: "none", field: func(c *Config) any { return &c.TraceExporter }},
+	{Key: "tracing.file", Env: "TRACE_FILE", Default: "traces.json", field: func(c *Config) any { return &c.TraceFile }},
+	{Key: "tracing.sample_ratio", Env: "TRACE_SAMPLE_RATIO", Default: "1", field: func(c *Config) any { return &c.TraceSampleRatio }},
+}
+
+func lookup(key string) *setting {
+	for i := range settings {
+		if settings[i].Key == key {
+			return &settings[i]
+		}
+	}
+	return nil
+}
+
+// stores raw into the setting's field, converted to the field's type
+func (s setting) parse(c *Config, raw string) error {
+	raw = strings.TrimSpace(raw)
+	switch field := s.field(c).(type) {
+	case *string:
+		*field = raw
+	case *int:
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		*field = value
+	case *float64:
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		*field = value
+	case *bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q, expected true or false", raw)
+		}
+		*field = value
+	case *time.Duration:
+		value, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q, expected a value like 30s or 15m", raw)
+		}
+		*field = value
+	default:
+		panic("config: unsupported field type for " + s.Key)
+	}
+	return nil
+}
+
+// returns the setting's value as it would be written in a config file
+func (s setting) format(c *Config) string {
+	switch field := s.field(c).(type) {
+	case *string:
+		return *field
+	case *int:
+		return strconv.Itoa(*field)
+	case *float64:
+		return strconv.FormatFloat(*field, 'g', -1, 64)
+	case *bool:
+		return strconv.FormatBool(*field)
+	case *time.Duration:
+		return formatDuration(*field)
+	default:
+		panic("config: unsupported field type for " + s.Key)
+	}
+}
+
+// copies the setting's value from src to dst
+func (s setting) copy(dst, src *Config) {
+	s.parse(dst, s.format(src))
+}
+
+// drops the zero units time.Duration.String adds, so 720h prints as 720h
+// rather than 720h0m0s
+func formatDuration(d time.Duration) string {
+	formatted := d.String()
+	if strings.HasSuffix(formatted, "m0s") {
+		formatted = strings.TrimSuffix(formatted, "0s")
+	}
+	if strings.HasSuffix(formatted, "h0m") {
+		formatted = strings.TrimSuffix(formatted, "0m")
+	}
+	return formatted
+}
+
+// checks the values that parsed but may still be out of range
+func (c *Config) validate() []error {
+	var errs []error
+	check := func(ok bool, key, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+		}
+	}
+
+	port, err := strconv.Atoi(c.Port)
+	check(err == nil && port >= 1 && port <= 65535, "server.port", "must be a port number between 1 and 65535, got %q", c.Port)
+	check(c.RequestTimeout >= 0, "server.request_timeout", "must not be negative, 0 disables it")
+	check(c.ExportTimeout >= 0, "server.export_timeout", "must not be negative, 0 disables it")
+	check(c.ShutdownTimeout > 0, "server.shutdown_timeout", "must be positive")
+	check(c.DrainDelay >= 0, "server.drain_delay", "must not be negative")
+
+	check(c.DBPath != "", "database.path", "is required")
+	check(c.DBMaxOpenConns >= 1, "database.max_open_conns", "must be at least 1")
+	check(c.DBMaxIdleConns >= 0 && c.DBMaxIdleConns <= c.DBMaxOpenConns, "database.max_idle_conns", "must be between 0 and database.max_open_conns (%d)", c.DBMaxOpenConns)
+	check(slices.Contains([]string{"wal", "delete", "truncate", "persist"}, c.DBJournalMode), "database.journal_mode", "must be wal, delete, truncate or persist, got %q", c.DBJournalMode)
+	check(slices.Contains([]string{"off", "normal", "full", "extra"}, c.DBSynchronous), "database.synchronous", "must be off, normal, full or extra, got %q", c.DBSynchronous)
+	check(c.DBBusyTimeout >= 0, "database.busy_timeout", "must not be negative")
+
+	check(c.JWTSecret != "", "auth.jwt_secret", "is required, set JWT_SECRET")
+	check(c.AccessTokenExpiry > 0, "auth.access_token_expiry", "must be positive")
+	check(c.RefreshTokenExpiry > 0, "auth.refresh_token_expiry", "must be positive")
+
+	check(c.MaxPageLimit >= 1, "pagination.max_limit", "must be at least 1")
+	check(c.DefaultPageLimit >= 1 && c.DefaultPageLimit <= c.MaxPageLimit, "pagination.default_limit", "must be between 1 and pagination.max_limit (%d)", c.MaxPageLimit)
+
+	check(c.DeletedRetention > 0, "retention.deleted", "must be positive")
+	check(c.PurgeInterval > 0, "retention.purge_interval", "must be positive")
+	check(c.IdempotencyTTL > 0, "idempotency.ttl", "must be positive")
+
+	check(c.BackupDir != "", "backup.dir", "is required")
+	check(c.BackupInterval >= 0, "backup.interval", "must not be negative, 0 disables scheduled backups")
+	check(c.BackupKeep >= 1, "backup.keep", "must be at least 1")
+	check(c.BackupMaxAge >= 0, "backup.max_age", "must not be negative, 0 disables it")
+
+	check(c.OutboxWorkers >= 1, "outbox.workers", "must be at least 1")
+	check(c.OutboxPollInterval > 0, "outbox.poll_interval", "must be positive")
+	check(c.OutboxLease > 0, "outbox.lease", "must be positive")
+	check(c.OutboxMaxAttempts >= 1, "outbox.max_attempts", "must be at least 1")
+	check(c.OutboxBackoffBase > 0, "outbox.backoff_base", "must be positive")
+	check(c.OutboxBackoffMax >= c.OutboxBackoffBase, "outbox.backoff_max", "must be at least outbox.backoff_base (%s)", c.OutboxBackoffBase)
+	check(c.OutboxRetention > 0, "outbox.retention", "must be positive")
+	check(c.WebhookTimeout > 0, "webhooks.timeout", "must be positive")
+	check(c.WebhookRetention > 0, "webhooks.delivery_retention", "must be positive")
+	check(c.StreamHeartbeat > 0, "stream.heartbeat", "must be positive")
+
+	_, err = scheduler.Parse(c.JobCompleteEvents)
+	check(err == nil, "jobs.complete_events", "%v", err)
+	_, err = scheduler.Parse(c.JobPurgeTokens)
+	check(err == nil, "jobs.purge_tokens", "%v", err)
+
+	check(slices.Contains([]string{"json", "text", "pretty"}, c.LogFormat), "log.format", "must be json, text or pretty, got %q", c.LogFormat)
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.LogLevel)) == nil, "log.level", "must be debug, info, warn or error, got %q", c.LogLevel)
+
+	check(slices.Contains([]string{"none", "otlp", "stdout", "file"}, c.TraceExporter), "tracing.exporter", "must be none, otlp, stdout or file, got %q", c.TraceExporter)
+	check(c.TraceExporter != "file" || c.TraceFile != "", "tracing.file", "is required when tracing.exporter is file")
+	check(c.TraceSampleRatio >= 0 && c.TraceSampleRatio <= 1, "tracing.sample_ratio", "must be between 0 and 1")
+
+	return errs
+}
@@ -0,0 +1,317 @@
+package db
+
+import (
+	"REST-API/config"
+	"REST-API/logging"
+	"REST-API/metrics"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backups are named after the moment they were taken, so they sort by age
+// and a restore can pick the one closest to a point in time
+const (
+	backupPrefix     = "backup-"
+	backupSuffix     = ".db"
+	backupTimeFormat = "20060102T150405.000Z"
+)
+
+var ErrBackupInProgress = errors.New("a backup is already in progress")
+
+// held while a backup is written, scheduled and on-demand backups don't overlap
+var backupMu sync.Mutex
+
+type BackupInfo struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"-"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// writes a consistent copy of the live database into dir with VACUUM INTO,
+// which doesn't block writers for longer than a normal read transaction.
+// The copy is written under a temporary name and renamed once complete, so a
+// crash never leaves a partial file that looks like a backup.
+func CreateBackup(ctx context.Context, dir string) (*BackupInfo, error) {
+	if !backupMu.TryLock() {
+		return nil, ErrBackupInProgress
+	}
+	defer backupMu.Unlock()
+
+	start := time.Now()
+	// the file name only keeps milliseconds, so CreatedAt matches ListBackups
+	info, err := createBackup(ctx, dir, start.UTC().Truncate(time.Millisecond))
+	if err != nil {
+		metrics.Backups.WithLabelValues("failure").Inc()
+		return nil, err
+	}
+
+	metrics.Backups.WithLabelValues("success").Inc()
+	metrics.BackupDuration.Observe(time.Since(start).Seconds())
+	metrics.BackupLastSuccess.SetToCurrentTime()
+	return info, nil
+}
+
+func createBackup(ctx context.Context, dir string, createdAt time.Time) (*BackupInfo, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("could not create backup directory: %w", err)
+	}
+
+	name := backupPrefix + createdAt.Format(backupTimeFormat) + backupSuffix
+	path := filepath.Join(dir, name)
+	partial := path + ".partial"
+	os.Remove(partial) // left behind by a crashed backup, VACUUM INTO won't overwrite it
+
+	if err := vacuumInto(ctx, partial); err != nil {
+		os.Remove(partial)
+		return nil, fmt.Errorf("could not write backup: %w", err)
+	}
+	if err := os.Rename(partial, path); err != nil {
+		os.Remove(partial)
+		return nil, err
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &BackupInfo{Name: name, Path: path, Size: stat.Size(), CreatedAt: createdAt}, nil
+}
+
+// VACUUM INTO only reads the live database, so it runs on a reader and
+// doesn't hold up writes. query_only refuses it though, as it writes a file,
+// so it's lifted on that one connection for the duration.
+func vacuumInto(ctx context.Context, path string) error {
+	conn, err := ReadDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if ReadDB != DB {
+		if _, err := conn.ExecContext(ctx, `PRAGMA query_only = 0`); err != nil {
+			return err
+		}
+		// a connection that can't be made read-only again must not go back to the pool
+		defer func() {
+			if _, err := conn.ExecContext(context.WithoutCancel(ctx), `PRAGMA query_only = 1`); err != nil {
+				conn.Raw(func(any) error { return driver.ErrBadConn })
+			}
+		}()
+	}
+
+	_, err = conn.ExecContext(ctx, `VACUUM INTO ?`, path)
+	return err
+}
+
+// returns the backups in dir, newest first. A missing directory has none.
+func ListBackups(dir string) ([]BackupInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []BackupInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	backups := make([]BackupInfo, 0, len(entries))
+	for _, entry := range entries {
+		createdAt, ok := parseBackupName(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+		stat, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, BackupInfo{
+			Name:      entry.Name(),
+			Path:      filepath.Join(dir, entry.Name()),
+			Size:      stat.Size(),
+			CreatedAt: createdAt,
+		})
+	}
+
+	slices.SortFunc(backups, func(a, b BackupInfo) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return backups, nil
+}
+
+func parseBackupName(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, backupPrefix) || !strings.HasSuffix(name, backupSuffix) {
+		return time.Time{}, false
+	}
+	stamp := strings.TrimSuffix(strings.TrimPrefix(name, backupPrefix), backupSuffix)
+	createdAt, err := time.Parse(backupTimeFormat, stamp)
+	return createdAt, err == nil
+}
+
+// takes a backup into BACKUP_DIR and prunes the ones the retention policy
+// no longer keeps, returning the backup and the names of those pruned. The
+// backup itself succeeded even if pruning fails, so that is only logged.
+func BackupAndPrune(ctx context.Context) (*BackupInfo, []string, error) {
+	cfg := config.Get()
+	logger := logging.FromContext(ctx)
+
+	info, err := CreateBackup(ctx, cfg.BackupDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	logger.Info("Backup written", "path", info.Path, "bytes", info.Size)
+
+	pruned, err := PruneBackups(cfg.BackupDir, cfg.BackupKeep, cfg.BackupMaxAge)
+	if err != nil {
+		logger.Error("Could not prune old backups", "error", err)
+	} else if len(pruned) > 0 {
+		logger.Info("Pruned old backups", "removed", pruned)
+	}
+	return info, pruned, nil
+}
+
+// deletes backups beyond the newest keep, and any older than maxAge when it
+// is positive. The newest backup is always kept, however old.
+func PruneBackups(dir string, keep int, maxAge time.Duration) ([]string, error) {
+	backups, err := ListBackups(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for i, backup := range backups {
+		expired := maxAge > 0 && time.Since(backup.CreatedAt) > maxAge
+		if i == 0 || (i < keep && !expired) {
+			continue
+		}
+		if err := os.Remove(backup.Path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, backup.Name)
+	}
+	return removed, nil
+}
+
+// finds a backup in dir by file name
+func FindBackup(dir, name string) (*BackupInfo, error) {
+	backups, err := ListBackups(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, backup := range backups {
+		if backup.Name == name {
+			return &backup, nil
+		}
+	}
+	return nil, fmt.Errorf("no backup named %q in %s", name, dir)
+}
+
+// finds the newest backup in dir taken at or before at, for point-in-time restores
+func FindBackupAt(dir string, at time.Time) (*BackupInfo, error) {
+	backups, err := ListBackups(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, backup := range backups {
+		if !backup.CreatedAt.After(at) {
+			return &backup, nil
+		}
+	}
+	return nil, fmt.Errorf("no backup in %s was taken at or before %s", dir, at.Format(time.RFC3339))
+}
+
+// checks that a backup is an intact database this build can run on
+func VerifyBackup(ctx context.Context, path string) error {
+	// read-only, so verifying never modifies the backup
+	backup, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer backup.Close()
+
+	var result string
+	if err := backup.QueryRowContext(ctx, `PRAGMA integrity_check`).Scan(&result); err != nil {
+		return fmt.Errorf("could not check integrity of %s: %w", path, err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("backup %s failed the integrity check: %s", path, result)
+	}
+
+	var version sql.NullInt64
+	if err := backup.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return fmt.Errorf("%s doesn't look like a backup of this database: %w", path, err)
+	}
+	if int(version.Int64) > LatestSchemaVersion() {
+		return fmt.Errorf("backup schema version %d is newer than this build supports (%d)", version.Int64, LatestSchemaVersion())
+	}
+	return nil
+}
+
+// replaces the database at dbPath with a verified copy of the backup. The
+// server must not be running. The current database, with its -wal and -shm
+// files, is moved aside rather than deleted, and its new name is returned.
+func RestoreBackup(ctx context.Context, backupPath, dbPath string) (string, error) {
+	if err := VerifyBackup(ctx, backupPath); err != nil {
+		return "", err
+	}
+
+	// copy next to the target first so the final step is an atomic rename
+	staged := dbPath + ".restoring"
+	if err := copyFile(backupPath, staged); err != nil {
+		os.Remove(staged)
+		return "", fmt.Errorf("could not stage backup: %w", err)
+	}
+
+	aside := dbPath + ".before-restore-" + time.Now().UTC().Format(backupTimeFormat)
+	moved := false
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		// a stale WAL next to the restored file would be replayed into it
+		err := os.Rename(dbPath+suffix, aside+suffix)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			os.Remove(staged)
+			return "", fmt.Errorf("could not move the current database aside: %w", err)
+		}
+		moved = moved || suffix == ""
+	}
+	if !moved {
+		aside = "" // there was no database to restore over
+	}
+
+	if err := os.Rename(staged, dbPath); err != nil {
+		return "", fmt.Errorf("could not swap in the backup, the previous database is at %s: %w", aside, err)
+	}
+	return aside, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := out.ReadFrom(in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
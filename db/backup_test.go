@@ -0,0 +1,129 @@
+package db
+
+import (
+	"REST-API/config"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// opens a file database with the full schema. Backups, restores and the
+// separate read pool all need a real file rather than :memory:
+func setupTestDB(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "api.db")
+	t.Setenv("JWT_SECRET", "test-secret-key")
+	t.Setenv("DB_PATH", path)
+	if err := config.Load(nil); err != nil {
+		t.Fatalf("could not load config: %v", err)
+	}
+
+	InitDB()
+	t.Cleanup(func() { Close() })
+	return path
+}
+
+func TestCreateAndRestoreBackup(t *testing.T) {
+	dbPath := setupTestDB(t)
+	dir := filepath.Join(t.TempDir(), "backups")
+	ctx := context.Background()
+
+	DB.Exec(`INSERT INTO users(email, password) VALUES ('kept@example.com', 'hashed')`)
+	backup, err := CreateBackup(ctx, dir)
+	if err != nil {
+		t.Fatalf("expected backup to succeed, got: %v", err)
+	}
+	if backup.Size == 0 {
+		t.Error("expected a non-empty backup file")
+	}
+
+	DB.Exec(`INSERT INTO users(email, password) VALUES ('lost@example.com', 'hashed')`)
+	Close()
+
+	aside, err := RestoreBackup(ctx, backup.Path, dbPath)
+	if err != nil {
+		t.Fatalf("expected restore to succeed, got: %v", err)
+	}
+	if _, err := os.Stat(aside); err != nil {
+		t.Errorf("expected the previous database to be kept at %s", aside)
+	}
+
+	restored, _ := sql.Open("sqlite", dbPath)
+	defer restored.Close()
+	var count int
+	restored.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count)
+	if count != 1 {
+		t.Errorf("expected only the user from before the backup, got %d users", count)
+	}
+}
+
+func TestVerifyBackup_RejectsCorruptFile(t *testing.T) {
+	dbPath := setupTestDB(t)
+
+	corrupt := filepath.Join(t.TempDir(), "backup-20240101T000000.000Z.db")
+	os.WriteFile(corrupt, []byte("definitely not a database"), 0o600)
+
+	if _, err := RestoreBackup(context.Background(), corrupt, dbPath); err == nil {
+		t.Fatal("expected a corrupt backup to be rejected")
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		t.Error("expected the live database to be left in place")
+	}
+}
+
+func TestPruneAndFindBackups(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().UTC()
+	ages := []time.Duration{time.Hour, 2 * time.Hour, 50 * time.Hour, 100 * time.Hour}
+	for _, age := range ages {
+		name := backupPrefix + now.Add(-age).Format(backupTimeFormat) + backupSuffix
+		os.WriteFile(filepath.Join(dir, name), []byte("x"), 0o600)
+	}
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a backup"), 0o600)
+
+	found, err := FindBackupAt(dir, now.Add(-90*time.Minute))
+	if err != nil || found.CreatedAt.After(now.Add(-2*time.Hour).Add(time.Second)) {
+		t.Errorf("expected the 2 hour old backup, got %+v (%v)", found, err)
+	}
+	if _, err := FindBackupAt(dir, now.Add(-200*time.Hour)); err == nil {
+		t.Error("expected no backup before the oldest one")
+	}
+
+	// keep 3, but anything older than 48h goes regardless
+	removed, err := PruneBackups(dir, 3, 48*time.Hour)
+	if err != nil || len(removed) != 2 {
+		t.Fatalf("expected 2 backups pruned, got %v (%v)", removed, err)
+	}
+
+	backups, _ := ListBackups(dir)
+	if len(backups) != 2 || !backups[0].CreatedAt.After(backups[1].CreatedAt) {
+		t.Errorf("expected the 2 newest backups, newest first, got %+v", backups)
+	}
+}
+
+func TestBackupAndPrune(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "backups")
+	t.Setenv("BACKUP_DIR", dir)
+	t.Setenv("BACKUP_KEEP", "1")
+	setupTestDB(t)
+	ctx := context.Background()
+
+	first, _, err := BackupAndPrune(ctx)
+	if err != nil {
+		t.Fatalf("expected backup to succeed, got: %v", err)
+	}
+	time.Sleep(2 * time.Millisecond) // names only have millisecond precision
+	second, pruned, err := BackupAndPrune(ctx)
+	if err != nil {
+		t.Fatalf("expected backup to succeed, got: %v", err)
+	}
+
+	backups, _ := ListBackups(dir)
+	if len(pruned) != 1 || pruned[0] != first.Name || len(backups) != 1 || backups[0].Name != second.Name {
+		t.Errorf("expected only the newest backup kept, pruned %v and kept %+v", pruned, backups)
+	}
+}
@@ -2,30 +2,113 @@ package db
 
 import (
 	"REST-API/config"
+	"context"
 	"database/sql"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
 
+	"github.com/XSAM/otelsql"
+	"go.opentelemetry.io/otel/attribute"
 	_ "modernc.org/sqlite"
 )
 
-var DB *sql.DB //global db instance (thread-safe connection pool manager)
+// SQLite allows one writer at a time, so writes go through a single
+// connection and wait their turn in database/sql instead of failing with
+// SQLITE_BUSY. With WAL, reads don't wait for writes and use their own pool.
+var (
+	DB     *sql.DB // the writer, for every write and transaction
+	ReadDB *sql.DB // read-only pool, for queries outside a transaction
+)
+
+// satisfied by both *sql.DB and *sql.Tx, so model code can run
+// standalone or inside a transaction owned by the caller
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
 
 func InitDB() {
-	var err error
-	DB, err = sql.Open("sqlite", config.App.DBPath)
+	cfg := config.Get()
 
-	if err != nil {
-		log.Fatal("Could not connect to database.")
+	// BEGIN IMMEDIATE takes the write lock up front, so a transaction can't
+	// fail halfway through because another process started writing
+	DB = open(dataSourceName(cfg.DBPath, connectionPragmas(cfg, false), "_txlock=immediate"))
+	DB.SetMaxOpenConns(1)
+
+	// every connection to :memory: is a separate empty database, so an
+	// in-memory database can only ever have the one
+	if cfg.DBPath == ":memory:" {
+		ReadDB = DB
+	} else {
+		ReadDB = open(dataSourceName(cfg.DBPath, connectionPragmas(cfg, true)))
+		ReadDB.SetMaxOpenConns(cfg.DBMaxOpenConns) // Maximum simultaneous read connections
+		ReadDB.SetMaxIdleConns(cfg.DBMaxIdleConns) // idle connections ready for reuse
+	}
+
+	// connections open lazily, make sure the pragmas are accepted before serving
+	for _, pool := range []*sql.DB{DB, ReadDB} {
+		if err := pool.Ping(); err != nil {
+			slog.Error("Could not connect to database", "error", err)
+			os.Exit(1)
+		}
 	}
 
-	DB.SetMaxOpenConns(10) // Maximum simultaneous database connections
-	DB.SetMaxIdleConns(5)  // idle connections ready for reuse
+	createTables()
+	runMigrations()
+}
 
-	_, err = DB.Exec("PRAGMA foreign_keys = ON")
+func open(dataSourceName string) *sql.DB {
+	// every query made with a request context becomes a span of that request's trace
+	pool, err := otelsql.Open("sqlite", dataSourceName,
+		otelsql.WithAttributes(attribute.String("db.system", "sqlite")),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{OmitConnResetSession: true, OmitRows: true}),
+	)
 	if err != nil {
-		panic("Couldn't enable foreign keys: " + err.Error())
+		slog.Error("Could not connect to database", "error", err)
+		os.Exit(1)
 	}
-	createTables()
+	return pool
+}
+
+// pragmas run on every new connection. They're per connection in SQLite,
+// setting them once with Exec only reaches whichever connection ran it.
+func connectionPragmas(cfg *config.Config, readOnly bool) []string {
+	pragmas := []string{
+		fmt.Sprintf("busy_timeout(%d)", cfg.DBBusyTimeout.Milliseconds()),
+		"foreign_keys(1)",
+		"synchronous(" + cfg.DBSynchronous + ")",
+	}
+	if readOnly {
+		return append(pragmas, "query_only(1)")
+	}
+	// the journal mode is stored in the database file, the writer sets it
+	return append([]string{"journal_mode(" + cfg.DBJournalMode + ")"}, pragmas...)
+}
+
+// appends the pragmas and any other driver parameters to the database path
+func dataSourceName(path string, pragmas []string, params ...string) string {
+	for _, pragma := range pragmas {
+		params = append(params, "_pragma="+url.QueryEscape(pragma))
+	}
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return path + separator + strings.Join(params, "&")
+}
+
+// closes both pools, after the server has stopped using them
+func Close() error {
+	err := DB.Close()
+	if ReadDB != DB {
+		err = errors.Join(err, ReadDB.Close())
+	}
+	return err
 }
 
 func createTables() {
@@ -67,6 +150,149 @@ func createTables() {
 	);
 	`
 
+	createCalendarFeedsTable := `
+	CREATE TABLE IF NOT EXISTS calendar_feeds (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL UNIQUE,
+		token TEXT NOT NULL UNIQUE,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+	);
+	`
+
+	createEventCoHostsTable := `
+	CREATE TABLE IF NOT EXISTS event_cohosts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		event_id INTEGER NOT NULL,
+		user_id INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(event_id, user_id),
+		FOREIGN KEY(event_id) REFERENCES events(id) ON DELETE CASCADE,
+		FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+	);
+	`
+
+	createNotificationsTable := `
+	CREATE TABLE IF NOT EXISTS notifications (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		event_id INTEGER,
+		type TEXT NOT NULL,
+		message TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		read_at DATETIME,
+		FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+		FOREIGN KEY(event_id) REFERENCES events(id) ON DELETE CASCADE
+	);
+	`
+
+	// previous versions of an event's details, numbered per event
+	createEventRevisionsTable := `
+	CREATE TABLE IF NOT EXISTS event_revisions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		event_id INTEGER NOT NULL,
+		revision INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		description TEXT NOT NULL,
+		location TEXT NOT NULL,
+		dateTime DATETIME NOT NULL,
+		end_date_time DATETIME,
+		created_at DATETIME NOT NULL,
+		UNIQUE(event_id, revision),
+		FOREIGN KEY(event_id) REFERENCES events(id) ON DELETE CASCADE
+	);
+	`
+
+	// responses replayed for retried requests carrying an Idempotency-Key
+	createIdempotencyKeysTable := `
+	CREATE TABLE IF NOT EXISTS idempotency_keys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		key TEXT NOT NULL,
+		request_hash TEXT NOT NULL,
+		status INTEGER NOT NULL DEFAULT 0,
+		headers TEXT,
+		body BLOB,
+		created_at DATETIME NOT NULL,
+		expires_at DATETIME NOT NULL,
+		UNIQUE(user_id, key),
+		FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+	);
+	`
+
+	// actor_id isn't a foreign key so entries outlive purged users
+	createAuditLogTable := `
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		actor_id INTEGER,
+		action TEXT NOT NULL,
+		resource_type TEXT NOT NULL,
+		resource_id INTEGER NOT NULL,
+		changes TEXT,
+		request_id TEXT NOT NULL DEFAULT '',
+		ip TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_audit_log_resource ON audit_log(resource_type, resource_id);
+	CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
+	CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+	BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END;
+	CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+	BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END;
+	`
+
+	// side effects written in the same transaction as the change causing them,
+	// delivered by the outbox workers
+	createOutboxTable := `
+	CREATE TABLE IF NOT EXISTS outbox (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		topic TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		status TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		available_at DATETIME NOT NULL,
+		locked_until DATETIME,
+		last_error TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL,
+		processed_at DATETIME
+	);
+	CREATE INDEX IF NOT EXISTS idx_outbox_status_available_at ON outbox(status, available_at);
+	`
+
+	// endpoints notified of changes, topics is a JSON array
+	createWebhooksTable := `
+	CREATE TABLE IF NOT EXISTS webhooks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		url TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		topics TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		active INTEGER NOT NULL DEFAULT 1,
+		created_at DATETIME NOT NULL,
+		FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_webhooks_user_id ON webhooks(user_id);
+	`
+
+	// one row per attempt to deliver to a webhook
+	createWebhookDeliveriesTable := `
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		webhook_id INTEGER NOT NULL,
+		message_id TEXT NOT NULL,
+		topic TEXT NOT NULL,
+		attempt INTEGER NOT NULL,
+		request_body TEXT NOT NULL,
+		status_code INTEGER NOT NULL DEFAULT 0,
+		error TEXT NOT NULL DEFAULT '',
+		duration_ms INTEGER NOT NULL,
+		created_at DATETIME NOT NULL,
+		FOREIGN KEY(webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id, id);
+	`
+
 	_, err := DB.Exec(createEventsTable)
 	if err != nil {
 		panic("Could not create events table: " + err.Error())
@@ -84,4 +310,40 @@ func createTables() {
 	if err != nil {
 		panic("Could not create refresh tokens table: " + err.Error())
 	}
+	_, err = DB.Exec(createCalendarFeedsTable)
+	if err != nil {
+		panic("Could not create calendar feeds table: " + err.Error())
+	}
+	_, err = DB.Exec(createEventCoHostsTable)
+	if err != nil {
+		panic("Could not create event co-hosts table: " + err.Error())
+	}
+	_, err = DB.Exec(createNotificationsTable)
+	if err != nil {
+		panic("Could not create notifications table: " + err.Error())
+	}
+	_, err = DB.Exec(createEventRevisionsTable)
+	if err != nil {
+		panic("Could not create event revisions table: " + err.Error())
+	}
+	_, err = DB.Exec(createIdempotencyKeysTable)
+	if err != nil {
+		panic("Could not create idempotency keys table: " + err.Error())
+	}
+	_, err = DB.Exec(createAuditLogTable)
+	if err != nil {
+		panic("Could not create audit log table: " + err.Error())
+	}
+	_, err = DB.Exec(createOutboxTable)
+	if err != nil {
+		panic("Could not create outbox table: " + err.Error())
+	}
+	_, err = DB.Exec(createWebhooksTable)
+	if err != nil {
+		panic("Could not create webhooks table: " + err.Error())
+	}
+	_, err = DB.Exec(createWebhookDeliveriesTable)
+	if err != nil {
+		panic("Could not create webhook deliveries table: " + err.Error())
+	}
 }
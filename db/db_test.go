@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestInitDB_ConnectionPragmas(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+
+	var journalMode string
+	DB.QueryRow(`PRAGMA journal_mode`).Scan(&journalMode)
+	if journalMode != "wal" {
+		t.Errorf("expected WAL journaling, got %q", journalMode)
+	}
+
+	// hold several readers at once so the pool has to open more than one
+	var conns []*sql.Conn
+	for i := 0; i < 3; i++ {
+		conn, err := ReadDB.Conn(ctx)
+		if err != nil {
+			t.Fatalf("could not get a read connection: %v", err)
+		}
+		conns = append(conns, conn)
+
+		var foreignKeys, busyTimeout int
+		conn.QueryRowContext(ctx, `PRAGMA foreign_keys`).Scan(&foreignKeys)
+		conn.QueryRowContext(ctx, `PRAGMA busy_timeout`).Scan(&busyTimeout)
+		if foreignKeys != 1 || busyTimeout != 5000 {
+			t.Errorf("connection %d: expected foreign_keys 1 and busy_timeout 5000, got %d and %d", i, foreignKeys, busyTimeout)
+		}
+	}
+	for _, conn := range conns {
+		conn.Close()
+	}
+
+	if _, err := ReadDB.Exec(`INSERT INTO users(email, password) VALUES ('reader@example.com', 'hashed')`); err == nil {
+		t.Error("expected the read pool to refuse writes")
+	}
+	if _, err := DB.Exec(`INSERT INTO events(name, description, location, dateTime, user_id) VALUES ('x', 'x', 'x', '2030-01-01', 999)`); err == nil {
+		t.Error("expected the writer to enforce foreign keys")
+	}
+}
+
+func TestInitDB_ConcurrentWritesDontFail(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tx, err := DB.BeginTx(ctx, nil)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer tx.Rollback()
+			email := fmt.Sprintf("user%d@example.com", i)
+			if _, err := tx.ExecContext(ctx, `INSERT INTO users(email, password) VALUES (?, 'hashed')`, email); err != nil {
+				errs <- err
+				return
+			}
+			// reads keep going while writes queue up
+			var count int
+			if err := ReadDB.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+				errs <- err
+				return
+			}
+			errs <- tx.Commit()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("expected every write to succeed, got: %v", err)
+		}
+	}
+	var count int
+	ReadDB.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count)
+	if count != 50 {
+		t.Errorf("expected 50 users, got %d", count)
+	}
+}
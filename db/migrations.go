@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+)
+
+// schema changes to tables that already exist in deployed databases.
+// createTables only handles brand new tables, since CREATE TABLE IF NOT
+// EXISTS never alters an existing one. Append new migrations to the end
+// and never edit one that has shipped.
+type migration struct {
+	Version    int
+	Name       string
+	Statements []string
+}
+
+var migrations = []migration{
+	{
+		Version: 1,
+		Name:    "add registrations.registered_at",
+		Statements: []string{
+			`ALTER TABLE registrations ADD COLUMN registered_at DATETIME`,
+			`UPDATE registrations SET registered_at = CURRENT_TIMESTAMP WHERE registered_at IS NULL`,
+		},
+	},
+	{
+		Version: 2,
+		Name:    "add event lifecycle columns",
+		Statements: []string{
+			`ALTER TABLE events ADD COLUMN end_date_time DATETIME`,
+			// events that existed before drafts were introduced were already live
+			`ALTER TABLE events ADD COLUMN status TEXT NOT NULL DEFAULT 'published'`,
+			`ALTER TABLE events ADD COLUMN cancel_reason TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE events ADD COLUMN cancelled_at DATETIME`,
+			`CREATE INDEX IF NOT EXISTS idx_events_status ON events(status)`,
+		},
+	},
+	{
+		Version: 3,
+		Name:    "add soft delete columns",
+		Statements: []string{
+			`ALTER TABLE events ADD COLUMN deleted_at DATETIME`,
+			`ALTER TABLE users ADD COLUMN deleted_at DATETIME`,
+		},
+	},
+	{
+		Version: 4,
+		Name:    "add events.version",
+		Statements: []string{
+			`ALTER TABLE events ADD COLUMN version INTEGER NOT NULL DEFAULT 1`,
+		},
+	},
+}
+
+func runMigrations() {
+	_, err := DB.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`)
+	if err != nil {
+		panic("Could not create schema migrations table: " + err.Error())
+	}
+
+	current, err := SchemaVersion(context.Background())
+	if err != nil {
+		panic("Could not read schema version: " + err.Error())
+	}
+
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		if err := applyMigration(m); err != nil {
+			panic(fmt.Sprintf("Could not apply migration %d (%s): %v", m.Version, m.Name, err))
+		}
+		slog.Info("Applied migration", "version", m.Version, "name", m.Name)
+	}
+}
+
+func applyMigration(m migration) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, statement := range m.Statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`INSERT INTO schema_migrations(version, name) VALUES (?, ?)`, m.Version, m.Name)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// returns the version of the last applied migration, 0 if none
+func SchemaVersion(ctx context.Context) (int, error) {
+	var version sql.NullInt64
+	err := ReadDB.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+// returns the version the code expects the schema to be at
+func LatestSchemaVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"REST-API/config"
+	"REST-API/db"
+	"REST-API/models"
+	"REST-API/scheduler"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// how often backupIfDue looks at the age of the newest backup
+const backupCheckInterval = time.Minute
+
+// registers the background maintenance jobs on their configured schedules.
+// They all run once at startup too, so frequent restarts can't starve them.
+func newScheduler(cfg *config.Config) (*scheduler.Scheduler, error) {
+	purgeEvery := "@every " + cfg.PurgeInterval.String()
+	onStart := scheduler.RunOnStart
+
+	jobs := scheduler.New()
+	err := errors.Join(
+		jobs.Add("complete-events", cfg.JobCompleteEvents, completeEndedEvents, onStart),
+		jobs.Add("purge-expired-tokens", cfg.JobPurgeTokens, purgeExpiredRefreshTokens, onStart),
+		jobs.Add("purge-deleted", purgeEvery, purgeDeletedRecords, onStart),
+		jobs.Add("purge-idempotency-keys", purgeEvery, purgeExpiredIdempotencyKeys, onStart),
+		jobs.Add("purge-outbox", purgeEvery, purgeProcessedOutboxMessages, onStart),
+		jobs.Add("purge-webhook-deliveries", purgeEvery, purgeWebhookDeliveries, onStart),
+		jobs.Add("backup", "@every "+backupCheckInterval.String(), backupIfDue, onStart),
+	)
+	return jobs, err
+}
+
+// moves published events whose end time has passed to completed
+func completeEndedEvents(ctx context.Context) error {
+	completed, err := models.CompleteEndedEvents(ctx)
+	if err != nil {
+		return fmt.Errorf("could not complete ended events: %w", err)
+	}
+	if completed > 0 {
+		slog.Info("Marked ended events as completed", "count", completed)
+	}
+	return nil
+}
+
+// drops refresh tokens that can no longer be used
+func purgeExpiredRefreshTokens(ctx context.Context) error {
+	purged, err := models.PurgeExpiredRefreshTokens(ctx)
+	if err != nil {
+		return fmt.Errorf("could not purge expired refresh tokens: %w", err)
+	}
+	if purged > 0 {
+		slog.Info("Purged expired refresh tokens", "count", purged)
+	}
+	return nil
+}
+
+// hard-deletes events and users that have been soft-deleted for longer
+// than the configured retention window
+func purgeDeletedRecords(ctx context.Context) error {
+	cutoff := time.Now().Add(-config.Get().DeletedRetention)
+
+	events, eventsErr := models.PurgeDeletedEvents(ctx, cutoff)
+	if eventsErr != nil {
+		eventsErr = fmt.Errorf("could not purge deleted events: %w", eventsErr)
+	} else if events > 0 {
+		slog.Info("Purged deleted events", "count", events)
+	}
+
+	// users are still purged when events fail
+	users, usersErr := models.PurgeDeletedUsers(ctx, cutoff)
+	if usersErr != nil {
+		usersErr = fmt.Errorf("could not purge deleted users: %w", usersErr)
+	} else if users > 0 {
+		slog.Info("Purged deleted users", "count", users)
+	}
+	return errors.Join(eventsErr, usersErr)
+}
+
+// drops stored Idempotency-Key responses once their TTL has passed
+func purgeExpiredIdempotencyKeys(ctx context.Context) error {
+	purged, err := models.PurgeExpiredIdempotencyKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("could not purge expired idempotency keys: %w", err)
+	}
+	if purged > 0 {
+		slog.Info("Purged expired idempotency keys", "count", purged)
+	}
+	return nil
+}
+
+// drops delivered outbox messages once they're past the retention window
+func purgeProcessedOutboxMessages(ctx context.Context) error {
+	cutoff := time.Now().Add(-config.Get().OutboxRetention)
+	purged, err := models.PurgeProcessedOutboxMessages(ctx, cutoff)
+	if err != nil {
+		return fmt.Errorf("could not purge processed outbox messages: %w", err)
+	}
+	if purged > 0 {
+		slog.Info("Purged processed outbox messages", "count", purged)
+	}
+	return nil
+}
+
+// trims webhook delivery logs to the retention window
+func purgeWebhookDeliveries(ctx context.Context) error {
+	cutoff := time.Now().Add(-config.Get().WebhookRetention)
+	purged, err := models.PurgeWebhookDeliveries(ctx, cutoff)
+	if err != nil {
+		return fmt.Errorf("could not purge webhook deliveries: %w", err)
+	}
+	if purged > 0 {
+		slog.Info("Purged webhook deliveries", "count", purged)
+	}
+	return nil
+}
+
+// takes a backup once the newest one is older than the backup interval, so
+// restarts don't each add another
+func backupIfDue(ctx context.Context) error {
+	cfg := config.Get()
+	if cfg.BackupInterval <= 0 {
+		return nil
+	}
+
+	backups, err := db.ListBackups(cfg.BackupDir)
+	if err != nil {
+		return fmt.Errorf("could not list backups: %w", err)
+	}
+	if len(backups) > 0 && time.Since(backups[0].CreatedAt) < cfg.BackupInterval {
+		return nil
+	}
+
+	// an on-demand backup is already being written
+	if _, _, err := db.BackupAndPrune(ctx); err != nil && !errors.Is(err, db.ErrBackupInProgress) {
+		return fmt.Errorf("scheduled backup failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package live
+
+import (
+	"REST-API/metrics"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// recent messages kept for clients resuming with Last-Event-ID
+	historySize = 1000
+
+	// messages queued for a subscriber before it's dropped as too slow. It
+	// can reconnect and resume from the history.
+	bufferSize = 64
+)
+
+var ErrClosed = errors.New("live updates are shutting down")
+
+// one change pushed to subscribers
+type Message struct {
+	ID      uint64 // increases by one with every message published
+	Type    string
+	EventID int
+	Public  bool // false for drafts, which only their organizers may see
+	Data    json.RawMessage
+}
+
+// a subscriber's view of the hub. Messages is closed when the hub shuts down
+// or the subscriber falls too far behind.
+type Subscription struct {
+	Messages <-chan Message
+
+	// messages published after the Last-Event-ID the subscriber resumed from
+	Replay []Message
+	// false when there was no Last-Event-ID, or it's too old or unknown to
+	// resume from, in which case the subscriber has to reload its state
+	Resumed bool
+	// the ID of the last message published before subscribing
+	LastID uint64
+
+	hub      *Hub
+	filter   func(Message) bool
+	messages chan Message
+}
+
+// fans messages out to subscribers and keeps a short history of them
+type Hub struct {
+	mu          sync.Mutex
+	lastID      uint64
+	history     []Message
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		// IDs continue from the current time, so ones handed out before a
+		// restart aren't mistaken for new ones
+		lastID:      uint64(time.Now().UnixMicro()),
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// sends data as JSON to every subscriber whose filter accepts it. Never
+// blocks on a subscriber.
+func (h *Hub) Publish(messageType string, eventID int, public bool, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		slog.Error("Could not encode live update", "type", messageType, "event_id", eventID, "error", err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	message := Message{ID: h.lastID, Type: messageType, EventID: eventID, Public: public, Data: payload}
+	h.history = append(h.history, message)
+	if len(h.history) > historySize {
+		h.history = h.history[1:]
+	}
+
+	for subscription := range h.subscribers {
+		if !subscription.filter(message) {
+			continue
+		}
+		select {
+		case subscription.messages <- message:
+		default:
+			h.remove(subscription)
+		}
+	}
+}
+
+// subscribes to the messages filter accepts. lastEventID is the client's
+// Last-Event-ID header, empty for a fresh connection.
+func (h *Hub) Subscribe(filter func(Message) bool, lastEventID string) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrClosed
+	}
+
+	messages := make(chan Message, bufferSize)
+	subscription := &Subscription{
+		Messages: messages,
+		LastID:   h.lastID,
+		hub:      h,
+		filter:   filter,
+		messages: messages,
+	}
+
+	if lastID, err := strconv.ParseUint(lastEventID, 10, 64); err == nil && h.canResume(lastID) {
+		subscription.Resumed = true
+		for _, message := range h.history {
+			if message.ID > lastID && filter(message) {
+				subscription.Replay = append(subscription.Replay, message)
+			}
+		}
+	}
+
+	h.subscribers[subscription] = struct{}{}
+	metrics.LiveSubscribers.Inc()
+	return subscription, nil
+}
+
+// whether every message after lastID is still in the history
+func (h *Hub) canResume(lastID uint64) bool {
+	if lastID == h.lastID {
+		return true
+	}
+	return lastID < h.lastID && len(h.history) > 0 && h.history[0].ID <= lastID+1
+}
+
+// ends every subscription and refuses new ones, for shutdown
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for subscription := range h.subscribers {
+		h.remove(subscription)
+	}
+}
+
+// must be called with h.mu held
+func (h *Hub) remove(subscription *Subscription) {
+	if _, ok := h.subscribers[subscription]; !ok {
+		return
+	}
+	delete(h.subscribers, subscription)
+	close(subscription.messages)
+	metrics.LiveSubscribers.Dec()
+}
+
+// stops the subscription, safe to call more than once
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+// the hub the models publish to
+var hub = NewHub()
+
+func Publish(messageType string, eventID int, public bool, data any) {
+	hub.Publish(messageType, eventID, public, data)
+}
+
+func Subscribe(filter func(Message) bool, lastEventID string) (*Subscription, error) {
+	return hub.Subscribe(filter, lastEventID)
+}
+
+func Close() {
+	hub.Close()
+}
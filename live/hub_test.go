@@ -0,0 +1,78 @@
+package live
+
+import (
+	"strconv"
+	"testing"
+)
+
+func all(Message) bool { return true }
+
+func TestHub_DeliversMatchingMessages(t *testing.T) {
+	hub := NewHub()
+	subscription, _ := hub.Subscribe(func(m Message) bool { return m.EventID == 1 }, "")
+	defer subscription.Close()
+
+	hub.Publish("event.updated", 2, true, nil)
+	hub.Publish("event.updated", 1, true, map[string]int{"id": 1})
+
+	message := <-subscription.Messages
+	if message.EventID != 1 || string(message.Data) != `{"id":1}` || message.ID != subscription.LastID+2 {
+		t.Errorf("expected only event 1's message, got %+v", message)
+	}
+	if subscription.Resumed {
+		t.Error("expected a fresh subscription not to count as resumed")
+	}
+}
+
+func TestHub_ResumesFromLastEventID(t *testing.T) {
+	hub := NewHub()
+	first, _ := hub.Subscribe(all, "")
+	first.Close()
+	for i := 0; i < 3; i++ {
+		hub.Publish("registrations", 1, true, i)
+	}
+
+	resumed, _ := hub.Subscribe(all, strconv.FormatUint(first.LastID+1, 10))
+	if !resumed.Resumed || len(resumed.Replay) != 2 || string(resumed.Replay[0].Data) != "1" {
+		t.Errorf("expected the two messages after the first replayed, got %+v", resumed)
+	}
+
+	for _, lastEventID := range []string{"garbage", "5", strconv.FormatUint(resumed.LastID+10, 10)} {
+		subscription, _ := hub.Subscribe(all, lastEventID)
+		if subscription.Resumed || len(subscription.Replay) != 0 {
+			t.Errorf("expected %q not to be resumable, got %+v", lastEventID, subscription)
+		}
+	}
+}
+
+func TestHub_DropsSlowSubscribers(t *testing.T) {
+	hub := NewHub()
+	subscription, _ := hub.Subscribe(all, "")
+
+	for i := 0; i <= bufferSize; i++ {
+		hub.Publish("registrations", 1, true, i)
+	}
+
+	received := 0
+	for range subscription.Messages {
+		received++
+	}
+	if received != bufferSize {
+		t.Errorf("expected the buffered messages then a closed channel, got %d", received)
+	}
+	subscription.Close() // already removed, must not panic
+}
+
+func TestHub_Close(t *testing.T) {
+	hub := NewHub()
+	subscription, _ := hub.Subscribe(all, "")
+
+	hub.Close()
+	if _, ok := <-subscription.Messages; ok {
+		t.Error("expected the subscription to be closed")
+	}
+	if _, err := hub.Subscribe(all, ""); err != ErrClosed {
+		t.Errorf("expected ErrClosed after closing, got %v", err)
+	}
+	hub.Publish("registrations", 1, true, 1) // must not panic
+}
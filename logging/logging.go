@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+type contextKey struct{}
+
+const redacted = "[REDACTED]"
+
+// attribute keys whose values are never written to the log
+var sensitiveKeyParts = []string{"password", "token", "secret", "authorization", "cookie"}
+
+// shared by every handler Setup builds, so SetLevel applies immediately
+var level slog.LevelVar
+
+// installs the default slog logger. format is json, text or pretty (colored,
+// for local development) and level is debug, info, warn or error.
+func Setup(w io.Writer, format, logLevel string) error {
+	if err := SetLevel(logLevel); err != nil {
+		return err
+	}
+
+	options := &slog.HandlerOptions{Level: &level, ReplaceAttr: redact}
+
+	var handler slog.Handler
+	switch format {
+	case "json":
+		handler = slog.NewJSONHandler(w, options)
+	case "text":
+		handler = slog.NewTextHandler(w, options)
+	case "pretty":
+		handler = newPrettyHandler(w, options)
+	default:
+		return fmt.Errorf("invalid log format %q, must be json, text or pretty", format)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// changes the minimum level of the default logger while running
+func SetLevel(logLevel string) error {
+	var parsed slog.Level
+	if err := parsed.UnmarshalText([]byte(logLevel)); err != nil {
+		return fmt.Errorf("invalid log level %q", logLevel)
+	}
+	level.Set(parsed)
+	return nil
+}
+
+// hides the values of attributes that look like credentials
+func redact(groups []string, attr slog.Attr) slog.Attr {
+	if isSensitiveKey(attr.Key) {
+		return slog.String(attr.Key, redacted)
+	}
+	return attr
+}
+
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// returns a copy of ctx carrying logger, see FromContext
+func WithContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// returns the request-scoped logger stored in ctx, or the default logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSetup_RedactsCredentials(t *testing.T) {
+	var out bytes.Buffer
+	if err := Setup(&out, "json", "info"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	slog.Info("login", "email", "a@example.com", "password", "secret123", "refresh_token", "abc",
+		slog.Group("headers", "Authorization", "jwt"))
+
+	var entry map[string]any
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatalf("expected a JSON log line, got %q", out.String())
+	}
+	if entry["email"] != "a@example.com" {
+		t.Errorf("expected email to be logged, got %v", entry["email"])
+	}
+	if entry["password"] != redacted || entry["refresh_token"] != redacted {
+		t.Errorf("expected credentials to be redacted, got %v", entry)
+	}
+	if headers, _ := entry["headers"].(map[string]any); headers["Authorization"] != redacted {
+		t.Errorf("expected nested authorization to be redacted, got %v", entry["headers"])
+	}
+}
+
+func TestSetup_LevelAndFormat(t *testing.T) {
+	var out bytes.Buffer
+	if err := Setup(&out, "pretty", "warn"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	slog.Info("hidden")
+	slog.Warn("shown", "token", "abc")
+	if strings.Contains(out.String(), "hidden") {
+		t.Error("expected info to be filtered at warn level")
+	}
+	if !strings.Contains(out.String(), "shown") || strings.Contains(out.String(), "abc") {
+		t.Errorf("expected a redacted warning, got %q", out.String())
+	}
+
+	if err := Setup(&out, "xml", "info"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	if err := Setup(&out, "json", "loud"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if FromContext(context.Background()) != slog.Default() {
+		t.Error("expected the default logger without a request-scoped one")
+	}
+
+	logger := slog.Default().With("request_id", "abc")
+	if FromContext(WithContext(context.Background(), logger)) != logger {
+		t.Error("expected the logger stored in the context")
+	}
+}
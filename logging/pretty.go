@@ -0,0 +1,134 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+const (
+	colorReset  = "\033[0m"
+	colorGray   = "\033[90m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorBlue   = "\033[34m"
+	colorCyan   = "\033[36m"
+)
+
+// a human-friendly colored handler for local development
+type prettyHandler struct {
+	w       io.Writer
+	mu      *sync.Mutex
+	options *slog.HandlerOptions
+	attrs   string // rendered by WithAttrs so later groups don't apply to them
+	group   string
+}
+
+func newPrettyHandler(w io.Writer, options *slog.HandlerOptions) *prettyHandler {
+	return &prettyHandler{w: w, mu: &sync.Mutex{}, options: options}
+}
+
+func (h *prettyHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.options.Level.Level()
+}
+
+func (h *prettyHandler) Handle(_ context.Context, record slog.Record) error {
+	var line strings.Builder
+
+	fmt.Fprintf(&line, "%s%s%s %s%-5s%s %s",
+		colorGray, record.Time.Format("15:04:05.000"), colorReset,
+		levelColor(record.Level), record.Level.String(), colorReset,
+		record.Message,
+	)
+
+	line.WriteString(h.attrs)
+	record.Attrs(func(attr slog.Attr) bool {
+		h.writeAttr(&line, attr)
+		return true
+	})
+	line.WriteString("\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, line.String())
+	return err
+}
+
+func (h *prettyHandler) writeAttr(line *strings.Builder, attr slog.Attr) {
+	if h.options.ReplaceAttr != nil {
+		attr = h.options.ReplaceAttr(nil, attr)
+	}
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+
+	key := attr.Key
+	if h.group != "" {
+		key = h.group + "." + key
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		nested := &prettyHandler{options: h.options, group: key}
+		for _, groupAttr := range attr.Value.Group() {
+			nested.writeAttr(line, groupAttr)
+		}
+		return
+	}
+
+	value := attr.Value.String()
+	if key == "status" {
+		value = statusColor(attr.Value.Int64()) + value + colorReset
+	}
+	fmt.Fprintf(line, " %s%s=%s%s", colorCyan, key, colorReset, value)
+}
+
+func (h *prettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var rendered strings.Builder
+	for _, attr := range attrs {
+		h.writeAttr(&rendered, attr)
+	}
+
+	clone := *h
+	clone.attrs += rendered.String()
+	return &clone
+}
+
+func (h *prettyHandler) WithGroup(name string) slog.Handler {
+	clone := *h
+	if clone.group != "" {
+		name = clone.group + "." + name
+	}
+	clone.group = name
+	return &clone
+}
+
+func levelColor(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return colorRed
+	case level >= slog.LevelWarn:
+		return colorYellow
+	case level >= slog.LevelInfo:
+		return colorGreen
+	default:
+		return colorGray
+	}
+}
+
+func statusColor(status int64) string {
+	switch {
+	case status >= 200 && status < 300:
+		return colorGreen
+	case status >= 300 && status < 400:
+		return colorBlue
+	case status >= 400 && status < 500:
+		return colorYellow
+	default:
+		return colorRed
+	}
+}
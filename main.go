@@ -3,14 +3,22 @@ package main
 import (
 	"REST-API/config"
 	"REST-API/db"
+	"REST-API/live"
+	"REST-API/logging"
+	"REST-API/metrics"
 	"REST-API/middleware"
+	"REST-API/outbox"
 	"REST-API/routes"
+	"REST-API/tracing"
 	"REST-API/utils"
+	"REST-API/webhooks"
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -18,50 +26,182 @@ import (
 )
 
 func main() {
-	config.Load()
+	// without a command name the server runs, so `events-api --config x` still works
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" {
+		printUsage(os.Stdout)
+		return
+	}
+
+	cmd := findCommand(name)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", name)
+		printUsage(os.Stderr)
+		os.Exit(2)
+	}
+
+	if err := cmd.Run(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runs the HTTP server until SIGINT or SIGTERM
+func serve(args []string) error {
+	fs := newFlagSet("serve")
+	configArgs := config.BindFlags(fs)
+	parseArgs(fs, args)
+
+	loadConfig(configArgs())
+	if err := logging.Setup(os.Stdout, config.Get().LogFormat, config.Get().LogLevel); err != nil {
+		slog.Error("Invalid logging configuration", "error", err)
+		os.Exit(1)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    config.Get().TraceExporter,
+		File:        config.Get().TraceFile,
+		SampleRatio: config.Get().TraceSampleRatio,
+	})
+	if err != nil {
+		slog.Error("Invalid tracing configuration", "error", err)
+		os.Exit(1)
+	}
 
 	db.InitDB()
+	metrics.RegisterDB(db.DB, "events_writer")
+	if db.ReadDB != db.DB {
+		metrics.RegisterDB(db.ReadDB, "events_reader")
+	}
 	utils.RegisterCustomValidations()
 
-	server := gin.Default()
+	jobs, err := newScheduler(config.Get())
+	if err != nil {
+		slog.Error("Invalid job schedule", "error", err)
+		os.Exit(1)
+	}
+	dispatcher := newDispatcher(config.Get())
+	sender := webhooks.NewSender(config.Get().WebhookTimeout, config.Get().WebhookAllowLocal)
+	webhooks.Register(dispatcher, sender)
+
+	// gin's own logger is replaced by middleware.Logger
+	server := gin.New()
+	server.Use(gin.Recovery())
 
 	server.Use(middleware.RequestID)
-	server.Use(middleware.Timeout(config.App.RequestTimeout))
+	server.Use(middleware.Metrics)
+	server.Use(middleware.Tracing()...)
 	server.Use(middleware.Logger())
+	server.Use(middleware.Timeout(config.Get().RequestTimeout, routes.TimeoutOverrides()))
+	// renders errors handlers attach with context.Error as problem+json
+	server.Use(middleware.Errors())
 
-	routes.RegisterRoutes(server)
+	routes.RegisterRoutes(server, jobs, sender)
 
 	httpServer := &http.Server{
-		Addr:    ":" + config.App.Port,
+		Addr:    ":" + config.Get().Port,
 		Handler: server,
 	}
+	// Shutdown waits for open requests, so event streams are ended as it starts
+	httpServer.RegisterOnShutdown(live.Close)
+
+	// periodic maintenance and side effects queued in the outbox, both
+	// stopped before the db is closed on shutdown
+	jobs.Start(context.Background())
+	dispatcher.Start(context.Background())
 
 	// goroutine to not block signal handling
 	go func() {
+		slog.Info("Server listening", "addr", httpServer.Addr)
 		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server error: %v", err)
+			slog.Error("Server error", "error", err)
+			os.Exit(1)
 		}
 	}()
 
-	//block until SIGINT or SIGTERM is received
+	//block until SIGINT or SIGTERM is received, reloading the config on SIGHUP
 	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	sig := <-quit
+	for sig == syscall.SIGHUP {
+		reloadConfig()
+		sig = <-quit
+	}
+
+	// fail readiness first so the load balancer stops routing new requests
+	// here before the listener closes. Interactive Ctrl+C skips the wait.
+	routes.BeginDrain()
+	if sig == syscall.SIGTERM && config.Get().DrainDelay > 0 {
+		slog.Info("Draining before shutdown", "delay", config.Get().DrainDelay.String())
+		time.Sleep(config.Get().DrainDelay)
+	}
 
-	log.Println("Server shutting down...")
+	slog.Info("Server shutting down...")
 
-	//allow up to 10 seconds for active requests to complete
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	//allow active requests some time to complete
+	ctx, cancel := context.WithTimeout(context.Background(), config.Get().ShutdownTimeout)
 	defer cancel()
 
 	if err := httpServer.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		slog.Error("Server forced to shutdown", "error", err)
+		os.Exit(1)
+	}
+
+	jobs.Stop()
+	dispatcher.Stop()
+
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("Error flushing traces", "error", err)
 	}
 
 	// close db connection after all requests have finished
-	if err := db.DB.Close(); err != nil {
-		log.Printf("Error closing database: %v", err)
+	if err := db.Close(); err != nil {
+		slog.Error("Error closing database", "error", err)
 	}
 
-	log.Println("Shutdown complete")
+	slog.Info("Shutdown complete")
+	return nil
+}
+
+// exits listing every invalid setting, so they can all be fixed at once
+func loadConfig(args []string) {
+	if err := config.Load(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
+}
+
+// applies the settings that are safe to change while running and keeps
+// the current configuration if the new one is invalid
+func reloadConfig() {
+	changed, ignored, err := config.Reload()
+	if err != nil {
+		slog.Error("Config reload rejected, keeping the current configuration", "error", err)
+		return
+	}
+
+	if err := logging.SetLevel(config.Get().LogLevel); err != nil {
+		slog.Error("Could not apply log level", "error", err)
+	}
+	if len(ignored) > 0 {
+		slog.Warn("Config changes need a restart to take effect", "settings", ignored)
+	}
+	slog.Info("Config reloaded", "changed", changed)
+}
+
+// delivers the side effects queued in the outbox. Topics are handled by the
+// features that consume them.
+func newDispatcher(cfg *config.Config) *outbox.Dispatcher {
+	return outbox.New(outbox.Options{
+		Workers:      cfg.OutboxWorkers,
+		PollInterval: cfg.OutboxPollInterval,
+		Lease:        cfg.OutboxLease,
+		MaxAttempts:  cfg.OutboxMaxAttempts,
+		BackoffBase:  cfg.OutboxBackoffBase,
+		BackoffMax:   cfg.OutboxBackoffMax,
+	})
 }
@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "events_api"
+
+// everything exposed on /metrics, kept separate from the global default
+// registry so tests and libraries can't leak collectors into it
+var registry = prometheus.NewRegistry()
+
+var (
+	// labeled by route template rather than raw path to keep cardinality bounded
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by method, route template and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by method, route template and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	Logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Login attempts, by result (success or failure).",
+	}, []string{"result"})
+
+	Registrations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "registrations_total",
+		Help:      "Event registrations, by action (created or cancelled).",
+	}, []string{"action"})
+
+	TimeoutAborts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "request_timeouts_total",
+		Help:      "Requests aborted by the timeout middleware.",
+	})
+
+	Backups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "backups_total",
+		Help:      "Database backups attempted, by result (success or failure).",
+	}, []string{"result"})
+
+	BackupDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "backup_duration_seconds",
+		Help:      "Time taken to write a successful database backup.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 4, 7),
+	})
+
+	// alert when this falls too far behind time()
+	BackupLastSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "backup_last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful database backup.",
+	})
+
+	JobRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_runs_total",
+		Help:      "Background job runs, by job and result (success, failure or skipped).",
+	}, []string{"job", "result"})
+
+	JobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_duration_seconds",
+		Help:      "Time taken by background job runs, by job.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 4, 8),
+	}, []string{"job"})
+
+	OutboxMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_messages_total",
+		Help:      "Outbox delivery attempts, by topic and result (delivered, retried or dead).",
+	}, []string{"topic", "result"})
+
+	LiveSubscribers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "live_subscribers",
+		Help:      "Clients currently connected to an event stream.",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		Logins,
+		Registrations,
+		TimeoutAborts,
+		Backups,
+		BackupDuration,
+		BackupLastSuccess,
+		JobRuns,
+		JobDuration,
+		OutboxMessages,
+		LiveSubscribers,
+	)
+
+	// start known series at zero so rates work before the first event
+	for _, result := range []string{"success", "failure"} {
+		Logins.WithLabelValues(result)
+	}
+	for _, action := range []string{"created", "cancelled"} {
+		Registrations.WithLabelValues(action)
+	}
+	for _, result := range []string{"success", "failure"} {
+		Backups.WithLabelValues(result)
+	}
+}
+
+// exposes connection pool stats from db.Stats(), call once after the db is opened
+func RegisterDB(db *sql.DB, name string) {
+	registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// serves the registry in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
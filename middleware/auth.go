@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"REST-API/logging"
 	"REST-API/utils"
 	"net/http"
 
@@ -11,22 +12,41 @@ func Authenticate(context *gin.Context) {
 	token := context.Request.Header.Get("Authorization")
 
 	if token == "" {
-		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-			"message": "authorization token required",
-		})
+		AbortWithProblem(context, http.StatusUnauthorized, "token_required", "authorization token required")
 		return
 	}
 
 	userID, role, err := utils.VerifyToken(token)
 	if err != nil {
-		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-			"message": "invalid or expired token",
-		})
+		AbortWithProblem(context, http.StatusUnauthorized, "invalid_token", "invalid or expired token")
 		return
 	}
 
 	context.Set("userId", userID)
 	context.Set("role", role)
+	withUserLogger(context, userID)
+
+	context.Next()
+}
+
+// adds the authenticated user to the request-scoped logger
+func withUserLogger(context *gin.Context, userID int) {
+	ctx := context.Request.Context()
+	logger := logging.FromContext(ctx).With("user_id", userID)
+	context.Request = context.Request.WithContext(logging.WithContext(ctx, logger))
+}
+
+// identifies the user if a valid token is present but never rejects the
+// request, for public routes that show more to owners (e.g. draft events)
+func OptionalAuthenticate(context *gin.Context) {
+	token := context.Request.Header.Get("Authorization")
+	if token != "" {
+		if userID, role, err := utils.VerifyToken(token); err == nil {
+			context.Set("userId", userID)
+			context.Set("role", role)
+			withUserLogger(context, userID)
+		}
+	}
 
 	context.Next()
 }
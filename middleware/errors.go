@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"REST-API/logging"
+	"REST-API/models"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const problemContentType = "application/problem+json"
+
+// 499 is nginx's non-standard status for a client that went away before
+// the response was ready
+const statusClientClosedRequest = 499
+
+// an RFC 7807 problem details body. Code is a stable identifier clients can
+// branch on, Errors carries per-field validation failures.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"requestId,omitempty"`
+	Errors    any    `json:"errors,omitempty"`
+}
+
+// an error for a request problem that never reaches the models layer, such
+// as a malformed ID or a missing header
+type HTTPError struct {
+	Status  int
+	Code    string
+	Message string
+	Errors  any
+}
+
+func (e *HTTPError) Error() string {
+	return e.Message
+}
+
+func NewHTTPError(status int, code, message string) *HTTPError {
+	return &HTTPError{Status: status, Code: code, Message: message}
+}
+
+var statusByKind = []struct {
+	kind   error
+	status int
+}{
+	{models.ErrNotFound, http.StatusNotFound},
+	{models.ErrConflict, http.StatusConflict},
+	{models.ErrTimeout, http.StatusGatewayTimeout},
+	{models.ErrCanceled, statusClientClosedRequest},
+	{models.ErrForbidden, http.StatusForbidden},
+	{models.ErrUnauthorized, http.StatusUnauthorized},
+	{models.ErrInvalid, http.StatusBadRequest},
+	{models.ErrPreconditionFailed, http.StatusPreconditionFailed},
+}
+
+// renders the last error a handler attached with context.Error as
+// application/problem+json, unless a response was already written.
+// Errors that aren't a models.Error or HTTPError are internal: they are
+// logged and the client only gets a generic 500. Must run after Logger.
+func Errors() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		context.Next()
+		RenderError(context)
+	}
+}
+
+// renders a pending error right away, for middleware that needs the final
+// response before Errors runs (e.g. to record it)
+func RenderError(context *gin.Context) {
+	if len(context.Errors) == 0 || context.Writer.Written() {
+		return
+	}
+
+	err := context.Errors.Last().Err
+	problem := problemFor(err)
+
+	logger := logging.FromContext(context.Request.Context())
+	switch {
+	case problem.Status >= http.StatusInternalServerError && problem.Status != http.StatusGatewayTimeout:
+		logger.Error("request failed", "error", err)
+	case problem.Status == http.StatusGatewayTimeout || problem.Status == statusClientClosedRequest:
+		logger.Warn("request aborted", "error", err)
+	}
+
+	WriteProblem(context, problem)
+}
+
+func problemFor(err error) Problem {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return Problem{Status: httpErr.Status, Code: httpErr.Code, Detail: httpErr.Message, Errors: httpErr.Errors}
+	}
+
+	var modelErr *models.Error
+	if errors.As(err, &modelErr) {
+		for _, mapping := range statusByKind {
+			if errors.Is(modelErr.Kind, mapping.kind) {
+				return Problem{Status: mapping.status, Code: modelErr.Code, Detail: modelErr.Message}
+			}
+		}
+	}
+
+	// the raw error may hold SQL or file paths, so it's never sent
+	return Problem{Status: http.StatusInternalServerError, Code: "internal_error", Detail: "an unexpected error occurred"}
+}
+
+// writes problem as the response and aborts the chain
+func WriteProblem(context *gin.Context, problem Problem) {
+	problem.complete(context.Request.URL.Path, GetRequestID(context))
+
+	// gin's JSON renderer keeps a Content-Type that is already set
+	context.Header("Content-Type", problemContentType)
+	context.AbortWithStatusJSON(problem.Status, problem)
+}
+
+// fills in the members derived from the status and request
+func (problem *Problem) complete(path, requestID string) {
+	if problem.Type == "" {
+		problem.Type = "about:blank"
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+		if problem.Status == statusClientClosedRequest {
+			problem.Title = "Client Closed Request"
+		}
+	}
+	problem.Instance = path
+	problem.RequestID = requestID
+}
+
+// attaches err for Errors to render and stops the handler chain
+func AbortWithError(context *gin.Context, err error) {
+	context.Error(err)
+	context.Abort()
+}
+
+// shorthand for aborting with an HTTPError
+func AbortWithProblem(context *gin.Context, status int, code, message string) {
+	AbortWithError(context, NewHTTPError(status, code, message))
+}
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"REST-API/logging"
+	"REST-API/models"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// headers that belong to a single request and are never replayed
+var unreplayedHeaders = []string{"X-Request-Id", "Date"}
+
+// records the response body while passing it through to the client
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
+
+// replays the first response to a POST carrying an Idempotency-Key, so
+// clients can safely retry on flaky networks. Keys are scoped per user and
+// kept for ttl; reusing one with a different request is rejected. The body
+// is read up front to hash it, so bodies over maxBody are refused with 413.
+// Must run after Authenticate.
+func Idempotency(ttl time.Duration, maxBody int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if c.Request.Method != http.MethodPost || key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			AbortWithProblem(c, http.StatusBadRequest, "invalid_idempotency_key", "Idempotency-Key must be at most 255 characters")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBody))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			AbortWithProblem(c, http.StatusRequestEntityTooLarge, "body_too_large", fmt.Sprintf("request body must be at most %d bytes", maxBody))
+			return
+		}
+		if err != nil {
+			AbortWithProblem(c, http.StatusBadRequest, "unreadable_body", "could not read request data")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		// the same key must always come with the same request
+		hash := sha256.New()
+		io.WriteString(hash, c.Request.Method+" "+c.Request.URL.RequestURI()+"\n")
+		hash.Write(body)
+		requestHash := hex.EncodeToString(hash.Sum(nil))
+
+		userID := c.GetInt("userId")
+		existing, err := models.ClaimIdempotencyKey(c.Request.Context(), userID, key, requestHash, ttl)
+		if err != nil {
+			AbortWithError(c, err)
+			return
+		}
+
+		if existing != nil {
+			replayIdempotentResponse(c, existing, requestHash)
+			return
+		}
+
+		// the response is stored even if the client has gone away or the
+		// request deadline passed, since the change has already been made
+		storeCtx := context.WithoutCancel(c.Request.Context())
+		completed := false
+		defer func() {
+			if !completed {
+				if err := models.ReleaseIdempotencyKey(storeCtx, userID, key); err != nil {
+					logging.FromContext(storeCtx).Error("could not release idempotency key", "error", err)
+				}
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		// errors are normally rendered further out, after this has returned
+		RenderError(c)
+
+		// server errors aren't cached so the retry gets another chance
+		if !recorder.Written() || recorder.Status() >= http.StatusInternalServerError {
+			return
+		}
+
+		header := recorder.Header().Clone()
+		for _, name := range unreplayedHeaders {
+			header.Del(name)
+		}
+		response := models.IdempotentResponse{
+			Key:    key,
+			UserID: userID,
+			Status: recorder.Status(),
+			Header: header,
+			Body:   recorder.body.Bytes(),
+		}
+		if err := response.Save(storeCtx); err != nil {
+			logging.FromContext(storeCtx).Error("could not store idempotent response", "error", err)
+			return
+		}
+		completed = true
+	}
+}
+
+func replayIdempotentResponse(c *gin.Context, existing *models.IdempotentResponse, requestHash string) {
+	if existing.RequestHash != requestHash {
+		AbortWithProblem(c, http.StatusUnprocessableEntity, "idempotency_key_reused", "Idempotency-Key was already used for a different request")
+		return
+	}
+
+	if existing.Status == 0 {
+		AbortWithProblem(c, http.StatusConflict, "idempotency_key_in_use", "a request with this Idempotency-Key is still being processed")
+		return
+	}
+
+	for name, values := range existing.Header {
+		for _, value := range values {
+			c.Writer.Header().Add(name, value)
+		}
+	}
+	c.Header("Idempotent-Replayed", "true")
+	c.Status(existing.Status)
+	c.Writer.Write(existing.Body)
+	c.Abort()
+}
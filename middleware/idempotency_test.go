@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"REST-API/config"
+	"REST-API/db"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// a server whose POST /events runs handler behind Idempotency, with the
+// user taken from an X-User header in place of a token
+func newIdempotencyServer(t *testing.T, handler gin.HandlerFunc) *gin.Engine {
+	t.Helper()
+
+	os.Setenv("JWT_SECRET", "test-secret-key")
+	os.Setenv("DB_PATH", ":memory:")
+	if err := config.Load(nil); err != nil {
+		t.Fatalf("could not load config: %v", err)
+	}
+	db.InitDB()
+	if _, err := db.DB.Exec(`INSERT INTO users(id, email, password) VALUES (1, 'one@example.com', 'hashed'), (2, 'two@example.com', 'hashed')`); err != nil {
+		t.Fatalf("could not seed test users: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	server.Use(RequestID, Errors(), func(c *gin.Context) {
+		userID, _ := strconv.Atoi(c.GetHeader("X-User"))
+		c.Set("userId", userID)
+	}, Idempotency(time.Hour, 1<<20))
+	server.POST("/events", handler)
+	return server
+}
+
+func postIdempotent(server *gin.Engine, user, key, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(body))
+	request.Header.Set("X-User", user)
+	request.Header.Set("Idempotency-Key", key)
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestIdempotency_ReplaysFirstResponse(t *testing.T) {
+	calls := 0
+	server := newIdempotencyServer(t, func(c *gin.Context) {
+		calls++
+		c.Header("Location", "/events/"+strconv.Itoa(calls))
+		c.JSON(http.StatusCreated, gin.H{"id": calls})
+	})
+
+	first := postIdempotent(server, "1", "key-1", `{"name":"Launch"}`)
+	retry := postIdempotent(server, "1", "key-1", `{"name":"Launch"}`)
+
+	if calls != 1 {
+		t.Fatalf("expected the handler to run once, ran %d times", calls)
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("expected the retry to get 201 %s, got %d %s", first.Body.String(), retry.Code, retry.Body.String())
+	}
+	if retry.Header().Get("Location") != "/events/1" || retry.Header().Get("Content-Type") != first.Header().Get("Content-Type") {
+		t.Errorf("expected the first response's headers, got %v", retry.Header())
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("expected the retry to be marked as replayed")
+	}
+	if retry.Header().Get("X-Request-Id") == first.Header().Get("X-Request-Id") {
+		t.Error("expected the retry to keep its own request ID")
+	}
+}
+
+func TestIdempotency_RejectsDifferentPayload(t *testing.T) {
+	calls := 0
+	server := newIdempotencyServer(t, func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusCreated, gin.H{"id": calls})
+	})
+
+	postIdempotent(server, "1", "key-1", `{"name":"Launch"}`)
+	reused := postIdempotent(server, "1", "key-1", `{"name":"Something else"}`)
+
+	if reused.Code != http.StatusUnprocessableEntity || !strings.Contains(reused.Body.String(), "idempotency_key_reused") {
+		t.Errorf("expected 422 idempotency_key_reused, got %d %s", reused.Code, reused.Body.String())
+	}
+	if calls != 1 {
+		t.Errorf("expected the handler to run once, ran %d times", calls)
+	}
+}
+
+func TestIdempotency_KeysArePerUser(t *testing.T) {
+	server := newIdempotencyServer(t, func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"user": c.GetInt("userId")})
+	})
+
+	postIdempotent(server, "1", "key-1", `{"name":"Launch"}`)
+	other := postIdempotent(server, "2", "key-1", `{"name":"Launch"}`)
+
+	if other.Code != http.StatusCreated || other.Body.String() != `{"user":2}` {
+		t.Errorf("expected user 2 to get their own response, got %d %s", other.Code, other.Body.String())
+	}
+	if other.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("expected user 2's request not to be a replay")
+	}
+}
+
+func TestIdempotency_ConflictWhileInProgress(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := newIdempotencyServer(t, func(c *gin.Context) {
+		close(started)
+		<-release
+		c.JSON(http.StatusCreated, gin.H{"id": 1})
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- postIdempotent(server, "1", "key-1", `{"name":"Launch"}`)
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the first request to start")
+	}
+
+	retry := postIdempotent(server, "1", "key-1", `{"name":"Launch"}`)
+	close(release)
+
+	if retry.Code != http.StatusConflict || !strings.Contains(retry.Body.String(), "idempotency_key_in_use") {
+		t.Errorf("expected 409 idempotency_key_in_use, got %d %s", retry.Code, retry.Body.String())
+	}
+
+	select {
+	case first := <-done:
+		if first.Code != http.StatusCreated {
+			t.Errorf("expected the first request to succeed, got %d", first.Code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the first request to finish")
+	}
+}
+
+func TestIdempotency_RejectsOversizedBodies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	server.Use(RequestID, Errors(), Idempotency(time.Hour, 16))
+	server.POST("/upload", func(c *gin.Context) {
+		t.Error("expected the handler not to run")
+	})
+
+	request := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(strings.Repeat("x", 17)))
+	request.Header.Set("Idempotency-Key", "key-1")
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusRequestEntityTooLarge || !strings.Contains(recorder.Body.String(), "body_too_large") {
+		t.Errorf("expected 413 body_too_large, got %d %s", recorder.Code, recorder.Body.String())
+	}
+}
@@ -1,80 +1,56 @@
 package middleware
 
 import (
-	"fmt"
+	"REST-API/logging"
+	"log/slog"
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// logs every request once it completes and gives handlers a request-scoped
+// logger, retrieved with logging.FromContext, that carries the request ID
+// (and the user ID once Authenticate has run). Must run after RequestID.
 func Logger() gin.HandlerFunc {
 	return func(context *gin.Context) {
-		requestID := GetRequestID(context)
-
-		shortRequestID := requestID
-		if len(requestID) > 8 {
-			shortRequestID = requestID[:8]
-		}
-
 		start := time.Now()
-		method := context.Request.Method
-		path := context.Request.URL.Path
 
-		// Log request start
-		methodColor := colorForMethod(method)
-		reset := "\033[0m"
-		requestIDColor := "\033[36m" //cyan
-
-		fmt.Printf("[%s%s%s] --> %s%s%s %s\n",
-			requestIDColor, shortRequestID, reset,
-			methodColor, method, reset,
-			path,
+		logger := slog.Default().With(
+			"request_id", GetRequestID(context),
+			"method", context.Request.Method,
+			"route", context.FullPath(),
 		)
+		if spanContext := trace.SpanContextFromContext(context.Request.Context()); spanContext.HasTraceID() {
+			logger = logger.With("trace_id", spanContext.TraceID().String())
+		}
+		context.Request = context.Request.WithContext(logging.WithContext(context.Request.Context(), logger))
 
 		context.Next()
 
 		status := context.Writer.Status()
-		duration := time.Since(start)
-		clientIP := context.ClientIP()
-
-		statusColor := colorForStatus(status)
-
-		// Log request completion
-		fmt.Printf("[%s%s%s] <-- %s%d%s | %v | %s | %s%s%s %s\n",
-			requestIDColor, shortRequestID, reset,
-			statusColor, status, reset,
-			duration,
-			clientIP,
-			methodColor, method, reset,
-			path,
-		)
-	}
-}
-
-func colorForStatus(status int) string {
-	switch {
-	case status >= 200 && status < 300:
-		return "\033[32m" // green for success
-	case status >= 300 && status < 400:
-		return "\033[34m" // blue for redirects
-	case status >= 400 && status < 500:
-		return "\033[33m" // yellow for client errors
-	default:
-		return "\033[31m" // red for server errors
-	}
-}
+		attrs := []any{
+			"status", status,
+			"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"bytes", max(context.Writer.Size(), 0),
+			"client_ip", context.ClientIP(),
+		}
+		// unmatched routes have no template, the raw path is safe to log then
+		if context.FullPath() == "" {
+			attrs = append(attrs, "path", context.Request.URL.Path)
+		}
+		if len(context.Errors) > 0 {
+			attrs = append(attrs, "errors", context.Errors.String())
+		}
 
-func colorForMethod(method string) string {
-	switch method {
-	case "GET":
-		return "\033[34m" // blue
-	case "POST":
-		return "\033[32m" // green
-	case "PUT":
-		return "\033[33m" // yellow
-	case "DELETE":
-		return "\033[31m" // red
-	default:
-		return "\033[37m" // white
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		logging.FromContext(context.Request.Context()).Log(context.Request.Context(), level, "request completed", attrs...)
 	}
 }
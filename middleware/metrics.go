@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"REST-API/metrics"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// records request counts and latency per route template
+func Metrics(context *gin.Context) {
+	start := time.Now()
+
+	context.Next()
+
+	route := context.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	status := strconv.Itoa(context.Writer.Status())
+
+	metrics.HTTPRequests.WithLabelValues(context.Request.Method, route, status).Inc()
+	metrics.HTTPRequestDuration.WithLabelValues(context.Request.Method, route, status).
+		Observe(time.Since(start).Seconds())
+}
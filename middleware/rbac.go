@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -11,16 +12,12 @@ func RequireAdmin(context *gin.Context) {
 	role, exists := context.Get("role")
 
 	if !exists {
-		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-			"message": "authentication required",
-		})
+		AbortWithProblem(context, http.StatusUnauthorized, "authentication_required", "authentication required")
 		return
 	}
 
 	if role != "admin" {
-		context.AbortWithStatusJSON(http.StatusForbidden, gin.H{
-			"message": "admin access required",
-		})
+		AbortWithProblem(context, http.StatusForbidden, "admin_required", "admin access required")
 		return
 	}
 
@@ -36,9 +33,7 @@ func RequireOwnerOrAdmin(context *gin.Context) {
 	resourceOwnerID, ownerExists := context.Get("resourceOwnerId")
 
 	if !roleExists || !userExists {
-		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-			"message": "authentication required",
-		})
+		AbortWithProblem(context, http.StatusUnauthorized, "authentication_required", "authentication required")
 		return
 	}
 
@@ -48,16 +43,12 @@ func RequireOwnerOrAdmin(context *gin.Context) {
 	}
 
 	if !ownerExists {
-		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"message": "resource owner not set",
-		})
+		AbortWithError(context, errors.New("resource owner not set"))
 		return
 	}
 
 	if userID != resourceOwnerID {
-		context.AbortWithStatusJSON(http.StatusForbidden, gin.H{
-			"message": "you don't have permission to access this resource",
-		})
+		AbortWithProblem(context, http.StatusForbidden, "forbidden", "you don't have permission to access this resource")
 		return
 	}
 
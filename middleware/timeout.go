@@ -1,28 +1,235 @@
 package middleware
 
 import (
+	"REST-API/metrics"
+	"bytes"
 	"context"
+	"encoding/json"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-func Timeout(timeout time.Duration) gin.HandlerFunc {
+// per-route timeouts keyed by method and route template, e.g.
+// "GET /events/:id/registrations/export". Zero or less disables the timeout.
+type TimeoutOverrides map[string]time.Duration
+
+// gives each request a deadline and answers 504 exactly once when it passes.
+// The rest of the chain runs against a buffered writer, so a handler that
+// ignores its context still times out and anything it writes afterwards is
+// discarded. Handlers that stream flush their writer, which sends the
+// buffered response on and means a late timeout can only cut it short.
+// Must run after Logger and before Errors.
+func Timeout(timeout time.Duration, overrides TimeoutOverrides) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		timeout := timeout
+		if override, ok := overrides[c.Request.Method+" "+c.FullPath()]; ok {
+			timeout = override
+		}
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
 		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
 		defer cancel()
-
-		// Replace request context with timeout context
 		c.Request = c.Request.WithContext(ctx)
 
-		c.Next()
+		// read up front, the handler goroutine owns c until it's done
+		path, requestID := c.Request.URL.Path, GetRequestID(c)
+
+		original := c.Writer
+		writer := &timeoutWriter{ResponseWriter: original, header: original.Header().Clone()}
+		c.Writer = writer
 
+		done := make(chan struct{})
+		var panicked any
+		go func() {
+			defer close(done)
+			defer func() { panicked = recover() }()
+			c.Next()
+		}()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded && writer.timeOut() {
+				writeTimeoutProblem(original, path, requestID)
+			}
+			// c goes back to gin's pool once this returns, so the handler
+			// has to finish first even though the client has its answer
+			<-done
+		}
+
+		c.Writer = original
+		if panicked != nil {
+			panic(panicked)
+		}
 		if ctx.Err() == context.DeadlineExceeded {
-			c.AbortWithStatusJSON(http.StatusGatewayTimeout, gin.H{
-				"message": "request timeout",
-				"error":   "the request took too long to process",
-			})
+			metrics.TimeoutAborts.Inc()
 		}
+		writer.finish()
+	}
+}
+
+func writeTimeoutProblem(w gin.ResponseWriter, path, requestID string) {
+	problem := Problem{
+		Status: http.StatusGatewayTimeout,
+		Code:   "timeout",
+		Detail: "the request took too long to process",
+	}
+	problem.complete(path, requestID)
+	body, _ := json.Marshal(problem)
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusGatewayTimeout)
+	w.Write(body)
+	w.Flush()
+}
+
+// holds the handler's response until it completes, the deadline passes or
+// the handler flushes
+type timeoutWriter struct {
+	gin.ResponseWriter // the real writer, only touched once committed
+
+	mu        sync.Mutex
+	header    http.Header
+	body      bytes.Buffer
+	status    int
+	wrote     bool
+	committed bool // flushed through, writes go straight to the real writer
+	timedOut  bool // a 504 was sent, writes are dropped
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.wrote && !w.timedOut {
+		w.status = code
+	}
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.wrote = true
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.wrote = true
+	if w.committed {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.body.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.committed {
+		return w.ResponseWriter.Size()
+	}
+	if !w.wrote {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *timeoutWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.wrote
+}
+
+// commits the response so far, for handlers that stream
+func (w *timeoutWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return
+	}
+	w.commit()
+	w.ResponseWriter.Flush()
+}
+
+// marks the response as timed out, reporting false if it was already
+// committed and can't be replaced by a 504
+func (w *timeoutWriter) timeOut() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.committed {
+		return false
+	}
+	w.timedOut = true
+	return true
+}
+
+// sends the buffered response once the handler has returned
+func (w *timeoutWriter) finish() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut || w.committed {
+		return
+	}
+	// nothing written leaves the real writer untouched, so gin can still
+	// send its own default responses (e.g. 404 for unmatched routes)
+	if !w.wrote && w.status == 0 {
+		return
+	}
+	w.commit()
+}
+
+// must be called with mu held
+func (w *timeoutWriter) commit() {
+	if w.committed {
+		return
+	}
+	w.committed = true
+
+	header := w.ResponseWriter.Header()
+	for name := range header {
+		if _, ok := w.header[name]; !ok {
+			header.Del(name)
+		}
+	}
+	for name, values := range w.header {
+		header[name] = values
+	}
+	// handlers keep using the same map, which is now the real one
+	w.header = header
+
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+	if w.body.Len() > 0 {
+		w.ResponseWriter.Write(w.body.Bytes())
+		w.body.Reset()
 	}
 }
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTimeoutServer(timeout time.Duration, overrides TimeoutOverrides, handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	server.Use(RequestID, Timeout(timeout, overrides), Errors())
+	server.GET("/slow", handler)
+	return server
+}
+
+func TestTimeout_SlowHandlerGetsOne504(t *testing.T) {
+	server := newTimeoutServer(20*time.Millisecond, nil, func(c *gin.Context) {
+		// ignores its context, as a handler stuck in a CPU loop would
+		time.Sleep(100 * time.Millisecond)
+		c.Header("X-Late", "true")
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "too late"})
+	})
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	if recorder.Code != http.StatusGatewayTimeout {
+		t.Fatalf("expected 504, got %d", recorder.Code)
+	}
+	if recorder.Header().Get("X-Late") != "" {
+		t.Error("expected headers set after the timeout to be discarded")
+	}
+	if recorder.Header().Get("Content-Type") != problemContentType {
+		t.Errorf("expected a problem+json body, got %q", recorder.Header().Get("Content-Type"))
+	}
+
+	var problem Problem
+	if err := json.Unmarshal(recorder.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("expected a single JSON body, got %q: %v", recorder.Body.String(), err)
+	}
+	if problem.Code != "timeout" || problem.Status != http.StatusGatewayTimeout {
+		t.Errorf("unexpected problem: %+v", problem)
+	}
+}
+
+func TestTimeout_FastHandlerPassesThrough(t *testing.T) {
+	server := newTimeoutServer(time.Second, nil, func(c *gin.Context) {
+		c.Header("X-Handler", "true")
+		c.JSON(http.StatusCreated, gin.H{"message": "ok"})
+	})
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", recorder.Code)
+	}
+	if recorder.Header().Get("X-Handler") != "true" {
+		t.Error("expected the handler's headers to be sent")
+	}
+	if recorder.Header().Get("X-Request-ID") == "" {
+		t.Error("expected headers set before the timeout middleware to be kept")
+	}
+	if recorder.Body.String() != `{"message":"ok"}` {
+		t.Errorf("unexpected body: %q", recorder.Body.String())
+	}
+}
+
+func TestTimeout_RouteOverride(t *testing.T) {
+	overrides := TimeoutOverrides{"GET /slow": time.Second}
+	server := newTimeoutServer(10*time.Millisecond, overrides, func(c *gin.Context) {
+		time.Sleep(50 * time.Millisecond)
+		if _, ok := c.Request.Context().Deadline(); !ok {
+			t.Error("expected the overridden timeout to still set a deadline")
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("expected the override to give the handler time to finish, got %d", recorder.Code)
+	}
+}
+
+func TestTimeout_FlushCommitsResponse(t *testing.T) {
+	server := newTimeoutServer(20*time.Millisecond, nil, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+		c.Writer.WriteString("first chunk\n")
+		c.Writer.Flush()
+		time.Sleep(100 * time.Millisecond)
+		c.Writer.WriteString("second chunk\n")
+	})
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	// the status was already sent, a late timeout can't replace it
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected the streamed 200 to stand, got %d", recorder.Code)
+	}
+	if recorder.Body.String() != "first chunk\nsecond chunk\n" {
+		t.Errorf("unexpected body: %q", recorder.Body.String())
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"REST-API/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// starts a span per request named after its route template, continuing any
+// W3C traceparent sent by the client, and tags it with the request ID so
+// traces can be found from logs. Must run after RequestID.
+func Tracing() []gin.HandlerFunc {
+	return []gin.HandlerFunc{
+		otelgin.Middleware(tracing.ServiceName),
+		func(context *gin.Context) {
+			trace.SpanFromContext(context.Request.Context()).
+				SetAttributes(attribute.String("http.request_id", GetRequestID(context)))
+			context.Next()
+		},
+	}
+}
@@ -0,0 +1,201 @@
+package models
+
+import (
+	"REST-API/db"
+	"REST-API/utils"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// audit actions, named <resource>.<verb>
+const (
+	AuditEventCreate     = "event.create"
+	AuditEventUpdate     = "event.update"
+	AuditEventDelete     = "event.delete"
+	AuditEventRestore    = "event.restore"
+	AuditEventPublish    = "event.publish"
+	AuditEventCancel     = "event.cancel"
+	AuditEventRegister   = "event.register"
+	AuditEventUnregister = "event.unregister"
+	AuditCoHostAdd       = "event.cohost_add"
+	AuditCoHostRemove    = "event.cohost_remove"
+	AuditUserCreate      = "user.create"
+	AuditUserDelete      = "user.delete"
+	AuditUserRestore     = "user.restore"
+	AuditUserRoleChange  = "user.role_change"
+	AuditUserPasswordSet = "user.password_reset"
+	AuditBackupCreate    = "backup.create"
+	AuditJobRun          = "job.run"
+	AuditOutboxRetry     = "outbox.retry"
+	AuditWebhookCreate   = "webhook.create"
+	AuditWebhookUpdate   = "webhook.update"
+	AuditWebhookDelete   = "webhook.delete"
+)
+
+type AuditEntry struct {
+	ID           int                          `json:"id"`
+	ActorID      *int                         `json:"actorId"` // nil for anonymous actions like signup
+	Action       string                       `json:"action"`
+	ResourceType string                       `json:"resourceType"`
+	ResourceID   int                          `json:"resourceId"`
+	Changes      map[string]utils.FieldChange `json:"changes,omitempty"`
+	RequestID    string                       `json:"requestId"`
+	IP           string                       `json:"ip"`
+	CreatedAt    time.Time                    `json:"createdAt"`
+}
+
+// narrows audit queries, zero values match everything
+type AuditFilter struct {
+	ActorID      int
+	Action       string
+	ResourceType string
+	ResourceID   int
+	RequestID    string
+	From         time.Time
+	To           time.Time
+}
+
+// appends an entry to the audit log, which can't be updated or deleted afterwards
+func RecordAudit(ctx context.Context, entry *AuditEntry) error {
+	var changes any
+	if len(entry.Changes) > 0 {
+		data, err := json.Marshal(entry.Changes)
+		if err != nil {
+			return err
+		}
+		changes = string(data)
+	}
+
+	query := `
+	INSERT INTO audit_log(actor_id, action, resource_type, resource_id, changes, request_id, ip, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	entry.CreatedAt = time.Now().UTC()
+	result, err := db.DB.ExecContext(ctx, query, entry.ActorID, entry.Action, entry.ResourceType,
+		entry.ResourceID, changes, entry.RequestID, entry.IP, entry.CreatedAt)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return timeoutError("writing audit log")
+		}
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	entry.ID = int(id)
+	return nil
+}
+
+// returns a page of audit entries matching the filter, newest first
+func GetAuditEntries(ctx context.Context, filter AuditFilter, page, limit int) ([]AuditEntry, int, error) {
+	condition, args := filter.whereClause()
+
+	var total int
+	err := db.ReadDB.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_log`+condition, args...).Scan(&total)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, 0, timeoutError("counting audit entries")
+		}
+		return nil, 0, err
+	}
+
+	entries := make([]AuditEntry, 0)
+	err = queryAuditEntries(ctx, filter, limit, (page-1)*limit, func(entry AuditEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
+
+// calls fn for every audit entry matching the filter, used for streaming exports
+func ForEachAuditEntry(ctx context.Context, filter AuditFilter, fn func(AuditEntry) error) error {
+	return queryAuditEntries(ctx, filter, -1, 0, fn)
+}
+
+func queryAuditEntries(ctx context.Context, filter AuditFilter, limit, offset int, fn func(AuditEntry) error) error {
+	condition, args := filter.whereClause()
+	query := `
+	SELECT id, actor_id, action, resource_type, resource_id, changes, request_id, ip, created_at
+	FROM audit_log` + condition + `
+	ORDER BY created_at DESC, id DESC
+	LIMIT ? OFFSET ?
+	`
+
+	rows, err := db.ReadDB.QueryContext(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return timeoutError("fetching audit entries")
+		}
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry AuditEntry
+		var changes sql.NullString
+		err := rows.Scan(&entry.ID, &entry.ActorID, &entry.Action, &entry.ResourceType,
+			&entry.ResourceID, &changes, &entry.RequestID, &entry.IP, &entry.CreatedAt)
+		if err != nil {
+			return err
+		}
+		if changes.Valid {
+			if err := json.Unmarshal([]byte(changes.String), &entry.Changes); err != nil {
+				return err
+			}
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (f AuditFilter) whereClause() (string, []any) {
+	var conditions []string
+	var args []any
+
+	if f.ActorID != 0 {
+		conditions = append(conditions, "actor_id = ?")
+		args = append(args, f.ActorID)
+	}
+	if f.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, f.Action)
+	}
+	if f.ResourceType != "" {
+		conditions = append(conditions, "resource_type = ?")
+		args = append(args, f.ResourceType)
+	}
+	if f.ResourceID != 0 {
+		conditions = append(conditions, "resource_id = ?")
+		args = append(args, f.ResourceID)
+	}
+	if f.RequestID != "" {
+		conditions = append(conditions, "request_id = ?")
+		args = append(args, f.RequestID)
+	}
+	if !f.From.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, f.From.UTC())
+	}
+	if !f.To.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, f.To.UTC())
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"REST-API/db"
+	"REST-API/utils"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAuditLog(t *testing.T) {
+	setupTestDB(t)
+
+	actorID := 1
+	entries := []AuditEntry{
+		{ActorID: &actorID, Action: AuditEventCreate, ResourceType: "event", ResourceID: 7, RequestID: "req-1"},
+		{ActorID: &actorID, Action: AuditEventUpdate, ResourceType: "event", ResourceID: 7, RequestID: "req-2",
+			Changes: map[string]utils.FieldChange{"name": {From: "Old", To: "New"}}},
+		{Action: AuditUserCreate, ResourceType: "user", ResourceID: 2, RequestID: "req-3"},
+	}
+	for i := range entries {
+		if err := RecordAudit(context.Background(), &entries[i]); err != nil {
+			t.Fatalf("expected no error recording audit entry, got: %v", err)
+		}
+	}
+
+	found, total, err := GetAuditEntries(context.Background(), AuditFilter{ResourceType: "event", ResourceID: 7}, 1, 10)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if total != 2 || len(found) != 2 {
+		t.Fatalf("expected 2 entries for event 7, got %d", total)
+	}
+	// newest first
+	if found[0].Action != AuditEventUpdate || found[0].Changes["name"].To != "New" {
+		t.Errorf("expected the update with its changes first, got %+v", found[0])
+	}
+
+	found, total, _ = GetAuditEntries(context.Background(), AuditFilter{From: time.Now().Add(time.Minute)}, 1, 10)
+	if total != 0 || len(found) != 0 {
+		t.Errorf("expected no entries after now, got %d", total)
+	}
+
+	// the log is append-only
+	if _, err := db.DB.Exec(`UPDATE audit_log SET action = 'tampered'`); err == nil {
+		t.Error("expected updating the audit log to fail")
+	}
+	if _, err := db.DB.Exec(`DELETE FROM audit_log`); err == nil {
+		t.Error("expected deleting from the audit log to fail")
+	}
+}
@@ -0,0 +1,184 @@
+package models
+
+import (
+	"REST-API/db"
+	"REST-API/utils"
+	"context"
+	"database/sql"
+	"time"
+)
+
+type CalendarFeed struct {
+	UserID    int       `json:"userId"`
+	Token     string    `json:"token"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// returns the user's calendar feed, creating one on first use
+func GetOrCreateCalendarFeed(ctx context.Context, userID int) (*CalendarFeed, error) {
+	feed, err := getCalendarFeedByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if feed != nil {
+		return feed, nil
+	}
+
+	token, err := utils.GenerateCalendarToken()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO calendar_feeds(user_id, token, updated_at) VALUES (?, ?, ?)`
+
+	now := time.Now()
+	_, err = db.DB.ExecContext(ctx, query, userID, token, now)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, timeoutError("creating calendar feed")
+		}
+		return nil, err
+	}
+
+	return &CalendarFeed{UserID: userID, Token: token, UpdatedAt: now}, nil
+}
+
+// replaces the feed token so previously shared URLs stop working
+func RotateCalendarToken(ctx context.Context, userID int) (*CalendarFeed, error) {
+	feed, err := GetOrCreateCalendarFeed(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := utils.GenerateCalendarToken()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE calendar_feeds SET token = ?, updated_at = ? WHERE user_id = ?`
+
+	now := time.Now()
+	_, err = db.DB.ExecContext(ctx, query, token, now, userID)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, timeoutError("rotating calendar token")
+		}
+		return nil, err
+	}
+
+	feed.Token = token
+	feed.UpdatedAt = now
+	return feed, nil
+}
+
+// looks up a feed by its secret token, returns nil if it doesn't exist or
+// its user is deleted. The feed works again if the user is restored.
+func GetCalendarFeedByToken(ctx context.Context, token string) (*CalendarFeed, error) {
+	query := `
+	SELECT f.user_id, f.token, f.updated_at
+	FROM calendar_feeds f
+	JOIN users u ON u.id = f.user_id
+	WHERE f.token = ? AND u.deleted_at IS NULL
+	`
+
+	var feed CalendarFeed
+	err := db.ReadDB.QueryRowContext(ctx, query, token).Scan(&feed.UserID, &feed.Token, &feed.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, timeoutError("fetching calendar feed")
+		}
+		return nil, err
+	}
+
+	return &feed, nil
+}
+
+func getCalendarFeedByUserID(ctx context.Context, userID int) (*CalendarFeed, error) {
+	query := `SELECT user_id, token, updated_at FROM calendar_feeds WHERE user_id = ?`
+
+	var feed CalendarFeed
+	err := db.ReadDB.QueryRowContext(ctx, query, userID).Scan(&feed.UserID, &feed.Token, &feed.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, timeoutError("fetching calendar feed")
+		}
+		return nil, err
+	}
+
+	return &feed, nil
+}
+
+// marks the user's feed as changed so subscribers see a new Last-Modified
+func touchCalendarFeed(ctx context.Context, exec db.Executor, userID int) error {
+	query := `UPDATE calendar_feeds SET updated_at = ? WHERE user_id = ?`
+
+	_, err := exec.ExecContext(ctx, query, time.Now(), userID)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return timeoutError("updating calendar feed")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// marks the feeds of everyone registered for an event as changed
+func touchEventCalendarFeeds(ctx context.Context, exec db.Executor, eventID int) error {
+	query := `
+	UPDATE calendar_feeds SET updated_at = ?
+	WHERE user_id IN (SELECT user_id FROM registrations WHERE event_id = ?)
+	`
+
+	_, err := exec.ExecContext(ctx, query, time.Now(), eventID)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return timeoutError("updating calendar feeds")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// returns every event the user is registered for, ordered by start time
+func GetRegisteredEvents(ctx context.Context, userID int) ([]Event, error) {
+	query := `
+	SELECT ` + eventColumnsOf("e") + `
+	FROM events e
+	INNER JOIN registrations r ON r.event_id = e.id
+	WHERE r.user_id = ? AND e.deleted_at IS NULL
+	ORDER BY e.dateTime
+	`
+
+	rows, err := db.ReadDB.QueryContext(ctx, query, userID)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, timeoutError("fetching registered events")
+		}
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]Event, 0)
+	for rows.Next() {
+		var event Event
+		err := rows.Scan(event.scanFields()...)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
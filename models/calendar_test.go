@@ -0,0 +1,88 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGetOrCreateCalendarFeed(t *testing.T) {
+	setupTestDB(t)
+
+	feed, err := GetOrCreateCalendarFeed(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("expected no error creating feed, got: %v", err)
+	}
+	if feed.Token == "" {
+		t.Fatal("expected feed token to be set")
+	}
+
+	again, _ := GetOrCreateCalendarFeed(context.Background(), 1)
+	if again.Token != feed.Token {
+		t.Error("expected the same token on subsequent calls")
+	}
+
+	rotated, err := RotateCalendarToken(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("expected no error rotating token, got: %v", err)
+	}
+	if rotated.Token == feed.Token {
+		t.Error("expected rotated token to differ from the old one")
+	}
+
+	old, _ := GetCalendarFeedByToken(context.Background(), feed.Token)
+	if old != nil {
+		t.Error("expected old token to no longer resolve")
+	}
+}
+
+func TestGetRegisteredEvents(t *testing.T) {
+	setupTestDB(t)
+
+	event := Event{
+		Name:        "Calendar Event",
+		Description: "An event for the calendar feed",
+		Location:    "Somewhere",
+		DateTime:    time.Now().Add(24 * time.Hour),
+		UserID:      1,
+		Status:      EventStatusPublished,
+	}
+	event.Save(context.Background())
+
+	registration := Registration{EventID: event.ID, UserID: 1}
+	if err := registration.Save(context.Background()); err != nil {
+		t.Fatalf("expected no error registering, got: %v", err)
+	}
+
+	events, err := GetRegisteredEvents(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(events) != 1 || events[0].ID != event.ID {
+		t.Errorf("expected the registered event in the feed, got %v", events)
+	}
+
+	registration.Cancel(context.Background())
+	events, _ = GetRegisteredEvents(context.Background(), 1)
+	if len(events) != 0 {
+		t.Errorf("expected feed to be empty after cancelling, got %d events", len(events))
+	}
+}
+
+func TestCalendarFeed_HiddenWhileUserDeleted(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+
+	feed, _ := GetOrCreateCalendarFeed(ctx, 1)
+	if err := DeleteUser(ctx, 1); err != nil {
+		t.Fatalf("could not delete user: %v", err)
+	}
+	if found, err := GetCalendarFeedByToken(ctx, feed.Token); err != nil || found != nil {
+		t.Fatalf("expected no feed for a deleted user, got %+v, %v", found, err)
+	}
+
+	RestoreUser(ctx, 1)
+	if found, _ := GetCalendarFeedByToken(ctx, feed.Token); found == nil {
+		t.Error("expected the feed back after restoring the user")
+	}
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"REST-API/db"
+	"context"
+	"strings"
+)
+
+// adds a user as co-host of an event, giving them organizer access
+func AddCoHost(ctx context.Context, eventID, userID int) error {
+	query := `INSERT INTO event_cohosts(event_id, user_id) VALUES (?, ?)`
+
+	_, err := db.DB.ExecContext(ctx, query, eventID, userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return ErrAlreadyCoHost
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			return timeoutError("adding co-host")
+		}
+		return err
+	}
+
+	return nil
+}
+
+func RemoveCoHost(ctx context.Context, eventID, userID int) error {
+	query := `DELETE FROM event_cohosts WHERE event_id = ? AND user_id = ?`
+
+	result, err := db.DB.ExecContext(ctx, query, eventID, userID)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return timeoutError("removing co-host")
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotCoHost
+	}
+
+	return nil
+}
+
+func IsEventCoHost(ctx context.Context, eventID, userID int) (bool, error) {
+	query := `SELECT COUNT(*) FROM event_cohosts WHERE event_id = ? AND user_id = ?`
+
+	var count int
+	err := db.ReadDB.QueryRowContext(ctx, query, eventID, userID).Scan(&count)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return false, timeoutError("checking co-host")
+		}
+		return false, err
+	}
+
+	return count > 0, nil
+}
@@ -0,0 +1,71 @@
+package models
+
+import "errors"
+
+// the kinds of failure a caller may need to tell apart, checked with
+// errors.Is. Anything that isn't one of these is an internal error.
+var (
+	ErrNotFound           = errors.New("not found")
+	ErrConflict           = errors.New("conflict")
+	ErrTimeout            = errors.New("timeout")
+	ErrCanceled           = errors.New("canceled")
+	ErrForbidden          = errors.New("forbidden")
+	ErrUnauthorized       = errors.New("unauthorized")
+	ErrInvalid            = errors.New("invalid")
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+// an expected failure with a message that is safe to show to clients.
+// Kind is one of the sentinels above and Code is a stable, machine-readable
+// identifier clients can branch on instead of the message.
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+func NewError(kind error, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func timeoutError(action string) error {
+	return NewError(ErrTimeout, "timeout", "request timeout while "+action)
+}
+
+func canceledError(action string) error {
+	return NewError(ErrCanceled, "canceled", "request was canceled while "+action)
+}
+
+var (
+	ErrEventNotFound        = NewError(ErrNotFound, "event_not_found", "event not found")
+	ErrDeletedEventNotFound = NewError(ErrNotFound, "deleted_event_not_found", "deleted event not found")
+	ErrUserNotFound         = NewError(ErrNotFound, "user_not_found", "user not found")
+	ErrDeletedUserNotFound  = NewError(ErrNotFound, "deleted_user_not_found", "deleted user not found")
+	ErrNotificationNotFound = NewError(ErrNotFound, "notification_not_found", "notification not found")
+	ErrWebhookNotFound      = NewError(ErrNotFound, "webhook_not_found", "webhook not found")
+
+	ErrDeadOutboxMessageNotFound = NewError(ErrNotFound, "dead_outbox_message_not_found", "dead-lettered outbox message not found")
+
+	ErrEventModified      = NewError(ErrPreconditionFailed, "event_modified", "event was modified by someone else")
+	ErrEventNotDraft      = NewError(ErrConflict, "event_not_draft", "only draft events can be published")
+	ErrEventEnded         = NewError(ErrConflict, "event_ended", "event is already cancelled or completed")
+	ErrEventNotPublished  = NewError(ErrConflict, "event_not_published", "only published events can be cancelled, delete drafts instead")
+	ErrRegistrationClosed = NewError(ErrConflict, "registration_closed", "event is not open for registration")
+	ErrAlreadyRegistered  = NewError(ErrConflict, "already_registered", "already registered for this event")
+	ErrNotRegistered      = NewError(ErrConflict, "not_registered", "you are not registered for this event")
+	ErrAlreadyCoHost      = NewError(ErrConflict, "already_cohost", "user is already a co-host of this event")
+	ErrNotCoHost          = NewError(ErrNotFound, "cohost_not_found", "user is not a co-host of this event")
+	ErrEmailTaken         = NewError(ErrConflict, "email_taken", "email already registered")
+
+	ErrInvalidCredentials  = NewError(ErrUnauthorized, "invalid_credentials", "invalid credentials")
+	ErrInvalidRefreshToken = NewError(ErrUnauthorized, "invalid_refresh_token", "invalid refresh token")
+	ErrRefreshTokenExpired = NewError(ErrUnauthorized, "refresh_token_expired", "refresh token expired")
+)
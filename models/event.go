@@ -4,33 +4,145 @@ import (
 	"REST-API/db"
 	"context"
 	"database/sql"
-	"errors"
 	"fmt"
+	"strings"
 	"time"
 )
 
 type Event struct {
-	ID          int       `json:"id"`
-	Name        string    `json:"name" validate:"required,min=3,max=100"`
-	Description string    `json:"description" validate:"required,min=10,max=500"`
-	Location    string    `json:"location" validate:"required,min=3,max=100"`
-	DateTime    time.Time `json:"dateTime" validate:"required,future_date"`
-	UserID      int       `json:"userId"`
+	ID           int        `json:"id"`
+	Name         string     `json:"name" validate:"required,min=3,max=100"`
+	Description  string     `json:"description" validate:"required,min=10,max=500"`
+	Location     string     `json:"location" validate:"required,min=3,max=100"`
+	DateTime     time.Time  `json:"dateTime" validate:"required,future_date"`
+	EndDateTime  *time.Time `json:"endDateTime,omitempty" validate:"omitempty,gtfield=DateTime"`
+	UserID       int        `json:"userId"`
+	Status       string     `json:"status"`
+	CancelReason string     `json:"cancelReason,omitempty"`
+	CancelledAt  *time.Time `json:"cancelledAt,omitempty"`
+	DeletedAt    *time.Time `json:"deletedAt,omitempty"`
+	Version      int        `json:"version"` // bumped on every change, used as the ETag
+}
+
+// lifecycle: draft -> published -> completed, draft/published -> cancelled
+const (
+	EventStatusDraft     = "draft"
+	EventStatusPublished = "published"
+	EventStatusCancelled = "cancelled"
+	EventStatusCompleted = "completed"
+)
+
+// columns selected for an Event, in the order scanFields expects
+const eventColumns = `id, name, description, location, dateTime, end_date_time, user_id, status, cancel_reason, cancelled_at, deleted_at, version`
+
+// eventColumns prefixed with a table alias, for queries that join events
+func eventColumnsOf(alias string) string {
+	columns := strings.Split(eventColumns, ", ")
+	for i, column := range columns {
+		columns[i] = alias + "." + column
+	}
+	return strings.Join(columns, ", ")
+}
+
+// scan destinations matching eventColumns
+func (e *Event) scanFields() []any {
+	return []any{
+		&e.ID,
+		&e.Name,
+		&e.Description,
+		&e.Location,
+		&e.DateTime,
+		&e.EndDateTime,
+		&e.UserID,
+		&e.Status,
+		&e.CancelReason,
+		&e.CancelledAt,
+		&e.DeletedAt,
+		&e.Version,
+	}
+}
+
+// the time after which the event counts as over
+func (e Event) EndsAt() time.Time {
+	if e.EndDateTime != nil {
+		return *e.EndDateTime
+	}
+	return e.DateTime
+}
+
+// drafts, cancelled and completed events can't take new registrations
+func (e Event) IsOpenForRegistration() bool {
+	return e.Status == EventStatusPublished
 }
 
 func (e *Event) Save(ctx context.Context) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := e.save(ctx, tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	publishEvent(LiveEventCreated, *e)
+	return nil
+}
+
+// saves the event inside a transaction owned by the caller,
+// committing or rolling back is left to them. Live streams aren't told.
+func (e *Event) SaveTx(ctx context.Context, tx *sql.Tx) error {
+	return e.save(ctx, tx)
+}
+
+// saves every event in one transaction, so either all of them are created
+// or none are. IDs are set on the events in place.
+func SaveEvents(ctx context.Context, events []Event) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i := range events {
+		if err := events[i].save(ctx, tx); err != nil {
+			return fmt.Errorf("saving event %d of %d: %w", i+1, len(events), err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		publishEvent(LiveEventCreated, event)
+	}
+	return nil
+}
+
+func (e *Event) save(ctx context.Context, exec db.Executor) error {
+	if e.Status == "" {
+		e.Status = EventStatusDraft
+	}
+
 	query := `
-	INSERT INTO events(name, description, location, dateTime, user_id) 
-	VALUES (?, ?, ?, ?, ?)
+	INSERT INTO events(name, description, location, dateTime, end_date_time, user_id, status) 
+	VALUES (?, ?, ?, ?, ?, ?, ?)
 	`
-	result, err := db.DB.ExecContext(ctx, query, e.Name, e.Description, e.Location, e.DateTime, e.UserID)
+	// stored in UTC so date comparisons in SQL stay consistent
+	result, err := exec.ExecContext(ctx, query, e.Name, e.Description, e.Location, e.DateTime.UTC(), utcOrNil(e.EndDateTime), e.UserID, e.Status)
 
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
-			return errors.New("request timeout while saving event")
+			return timeoutError("saving event")
 		}
 		if ctx.Err() == context.Canceled {
-			return errors.New("request was canceled while saving event")
+			return canceledError("saving event")
 		}
 		return err
 	}
@@ -41,29 +153,31 @@ func (e *Event) Save(ctx context.Context) error {
 	}
 
 	e.ID = int(id)
-	return nil
+	e.Version = 1
+	return enqueueOutbox(ctx, exec, OutboxEventCreated, e)
 }
 
 func GetAllEvents(ctx context.Context, page, limit int) ([]Event, int, error) {
 
+	// drafts are only visible to their owner, through /me/events
 	var total int
-	countQuery := `SELECT COUNT(*) FROM events`
+	countQuery := `SELECT COUNT(*) FROM events WHERE status != 'draft' AND deleted_at IS NULL`
 
-	err := db.DB.QueryRowContext(ctx, countQuery).Scan(&total)
+	err := db.ReadDB.QueryRowContext(ctx, countQuery).Scan(&total)
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
-			return nil, 0, errors.New("request timeout while counting events")
+			return nil, 0, timeoutError("counting events")
 		}
 		return nil, 0, err
 	}
 
 	offset := (page - 1) * limit
 
-	query := `SELECT id, name, description, location, dateTime, user_id FROM events LIMIT ? OFFSET ?`
-	rows, err := db.DB.QueryContext(ctx, query, limit, offset)
+	query := `SELECT ` + eventColumns + ` FROM events WHERE status != 'draft' AND deleted_at IS NULL LIMIT ? OFFSET ?`
+	rows, err := db.ReadDB.QueryContext(ctx, query, limit, offset)
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
-			return nil, 0, errors.New("request timeout while fetching events")
+			return nil, 0, timeoutError("fetching events")
 		}
 		return nil, 0, err
 	}
@@ -72,14 +186,99 @@ func GetAllEvents(ctx context.Context, page, limit int) ([]Event, int, error) {
 	events := make([]Event, 0)
 	for rows.Next() {
 		var event Event
-		err := rows.Scan(
-			&event.ID,
-			&event.Name,
-			&event.Description,
-			&event.Location,
-			&event.DateTime,
-			&event.UserID,
-		)
+		err := rows.Scan(event.scanFields()...)
+		if err != nil {
+			return nil, 0, err
+		}
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
+
+// calls fn for every event that isn't deleted, drafts included, in ID order.
+// Used for bulk exports.
+func ForEachEvent(ctx context.Context, fn func(Event) error) error {
+	query := `SELECT ` + eventColumns + ` FROM events WHERE deleted_at IS NULL ORDER BY id`
+	rows, err := db.ReadDB.QueryContext(ctx, query)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return timeoutError("fetching events")
+		}
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event Event
+		if err := rows.Scan(event.scanFields()...); err != nil {
+			return err
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// filters for listing events relative to the current time
+const (
+	TimeFilterAll      = ""
+	TimeFilterUpcoming = "upcoming"
+	TimeFilterPast     = "past"
+)
+
+// returns the SQL condition and ordering for a time filter on the given column
+func timeFilterClause(filter, column string) (condition string, order string) {
+	switch filter {
+	case TimeFilterUpcoming:
+		return " AND " + column + " >= ?", column + " ASC"
+	case TimeFilterPast:
+		return " AND " + column + " < ?", column + " DESC"
+	default:
+		return "", column + " ASC"
+	}
+}
+
+// returns a page of events created by the user
+func GetEventsByUser(ctx context.Context, userID int, filter string, page, limit int) ([]Event, int, error) {
+	condition, order := timeFilterClause(filter, "dateTime")
+	args := []any{userID}
+	if condition != "" {
+		args = append(args, time.Now().UTC())
+	}
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM events WHERE user_id = ? AND deleted_at IS NULL` + condition
+
+	err := db.ReadDB.QueryRowContext(ctx, countQuery, args...).Scan(&total)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, 0, timeoutError("counting events")
+		}
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + eventColumns + ` FROM events WHERE user_id = ? AND deleted_at IS NULL` +
+		condition + ` ORDER BY ` + order + ` LIMIT ? OFFSET ?`
+	rows, err := db.ReadDB.QueryContext(ctx, query, append(args, limit, (page-1)*limit)...)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, 0, timeoutError("fetching events")
+		}
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	events := make([]Event, 0)
+	for rows.Next() {
+		var event Event
+		err := rows.Scan(event.scanFields()...)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -94,26 +293,19 @@ func GetAllEvents(ctx context.Context, page, limit int) ([]Event, int, error) {
 }
 
 func GetEventByID(ctx context.Context, id int) (*Event, error) {
-	query := `SELECT id, name, description, location, dateTime, user_id FROM events WHERE id = ?`
+	query := `SELECT ` + eventColumns + ` FROM events WHERE id = ? AND deleted_at IS NULL`
 
-	row := db.DB.QueryRowContext(ctx, query, id)
+	row := db.ReadDB.QueryRowContext(ctx, query, id)
 
 	var event Event
-	err := row.Scan(
-		&event.ID,
-		&event.Name,
-		&event.Description,
-		&event.Location,
-		&event.DateTime,
-		&event.UserID,
-	)
+	err := row.Scan(event.scanFields()...)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		if ctx.Err() == context.DeadlineExceeded {
-			return nil, errors.New("request timeout while fetching event")
+			return nil, timeoutError("fetching event")
 		}
 		return nil, err
 	}
@@ -121,20 +313,32 @@ func GetEventByID(ctx context.Context, id int) (*Event, error) {
 	return &event, nil
 }
 
-func (event Event) Update(ctx context.Context) error {
+// saves the event's details, keeping the version being replaced as a revision.
+// Fails if the event changed since event.Version was read.
+func (event *Event) Update(ctx context.Context) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := saveEventRevision(ctx, tx, event.ID); err != nil {
+		return err
+	}
+
 	query := `
 	UPDATE events
-	SET name = ?, description = ?, location = ?, dateTime = ?
-	WHERE id = ?
+	SET name = ?, description = ?, location = ?, dateTime = ?, end_date_time = ?, version = version + 1
+	WHERE id = ? AND version = ? AND deleted_at IS NULL
 	`
 
-	result, err := db.DB.ExecContext(ctx, query, event.Name, event.Description, event.Location, event.DateTime, event.ID)
+	result, err := tx.ExecContext(ctx, query, event.Name, event.Description, event.Location, event.DateTime.UTC(), utcOrNil(event.EndDateTime), event.ID, event.Version)
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
-			return errors.New("request timeout while updating event")
+			return timeoutError("updating event")
 		}
 		if ctx.Err() == context.Canceled {
-			return errors.New("request was canceled while updating event")
+			return canceledError("updating event")
 		}
 		return err
 	}
@@ -145,22 +349,111 @@ func (event Event) Update(ctx context.Context) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("event with id %d not found", event.ID)
+		return eventWriteConflict(ctx, tx, event.ID)
 	}
 
+	if err := touchEventCalendarFeeds(ctx, tx, event.ID); err != nil {
+		return err
+	}
+
+	updated := *event
+	updated.Version++
+	if err := enqueueOutbox(ctx, tx, OutboxEventUpdated, updated); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	event.Version++
+	publishEvent(LiveEventUpdated, *event)
 	return nil
 }
 
+// soft-deletes the event, it's hidden everywhere but can be restored by an
+// admin until PurgeDeletedEvents removes it for good.
+// Fails if the event changed since event.Version was read.
 func (event Event) Delete(ctx context.Context) error {
-	query := `DELETE FROM events WHERE id = ?`
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	deletedAt := time.Now().UTC()
+	query := `
+	UPDATE events SET deleted_at = ?, version = version + 1
+	WHERE id = ? AND version = ? AND deleted_at IS NULL
+	`
 
-	result, err := db.DB.ExecContext(ctx, query, event.ID)
+	result, err := tx.ExecContext(ctx, query, deletedAt, event.ID, event.Version)
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
-			return errors.New("request timeout while deleting event")
+			return timeoutError("deleting event")
 		}
 		if ctx.Err() == context.Canceled {
-			return errors.New("request was canceled while deleting event")
+			return canceledError("deleting event")
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return eventWriteConflict(ctx, tx, event.ID)
+	}
+
+	if err := touchEventCalendarFeeds(ctx, tx, event.ID); err != nil {
+		return err
+	}
+
+	deleted := event
+	deleted.DeletedAt = &deletedAt
+	deleted.Version++
+	if err := enqueueOutbox(ctx, tx, OutboxEventDeleted, deleted); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	publishEventDeleted(event)
+	return nil
+}
+
+// explains why a versioned write matched no rows: the event is gone, or
+// someone else changed it first
+func eventWriteConflict(ctx context.Context, exec db.Executor, id int) error {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM events WHERE id = ? AND deleted_at IS NULL)`
+	if err := exec.QueryRowContext(ctx, query, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrEventNotFound
+	}
+	return ErrEventModified
+}
+
+// moves a draft event to published so it becomes visible and open for registration
+func (e *Event) Publish(ctx context.Context) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE events SET status = ?, version = version + 1 WHERE id = ? AND status = ? AND deleted_at IS NULL`
+
+	result, err := tx.ExecContext(ctx, query, EventStatusPublished, e.ID, EventStatusDraft)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return timeoutError("publishing event")
 		}
 		return err
 	}
@@ -169,10 +462,253 @@ func (event Event) Delete(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	if rowsAffected == 0 {
+		return ErrEventNotDraft
+	}
+
+	published := *e
+	published.Status = EventStatusPublished
+	published.Version++
+	if err := enqueueOutbox(ctx, tx, OutboxEventUpdated, published); err != nil {
+		return err
+	}
 
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	*e = published
+	publishEvent(LiveEventUpdated, published)
+	return nil
+}
+
+// cancels a published event and notifies everyone registered for it. The
+// event stays readable so registrants can see why it was cancelled. Drafts
+// can't be cancelled, since that would make them visible to everyone; they
+// are deleted instead.
+func (e *Event) Cancel(ctx context.Context, reason string) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	cancelledAt := time.Now().UTC()
+	query := `
+	UPDATE events
+	SET status = ?, cancel_reason = ?, cancelled_at = ?, version = version + 1
+	WHERE id = ? AND status = ? AND deleted_at IS NULL
+	`
+
+	result, err := tx.ExecContext(ctx, query, EventStatusCancelled, reason, cancelledAt, e.ID, EventStatusPublished)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return timeoutError("cancelling event")
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
 	if rowsAffected == 0 {
-		return fmt.Errorf("event with id %d not found", event.ID)
+		var status string
+		err := tx.QueryRowContext(ctx, `SELECT status FROM events WHERE id = ? AND deleted_at IS NULL`, e.ID).Scan(&status)
+		if err == sql.ErrNoRows {
+			return ErrEventNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if status == EventStatusDraft {
+			return ErrEventNotPublished
+		}
+		return ErrEventEnded
 	}
 
+	message := fmt.Sprintf("%q has been cancelled: %s", e.Name, reason)
+	if err := notifyRegistrants(ctx, tx, e.ID, NotificationEventCancelled, message); err != nil {
+		return err
+	}
+	if err := touchEventCalendarFeeds(ctx, tx, e.ID); err != nil {
+		return err
+	}
+
+	cancelled := *e
+	cancelled.Status = EventStatusCancelled
+	cancelled.CancelReason = reason
+	cancelled.CancelledAt = &cancelledAt
+	cancelled.Version++
+	if err := enqueueOutbox(ctx, tx, OutboxEventCancelled, cancelled); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	*e = cancelled
+	publishEvent(LiveEventCancelled, cancelled)
 	return nil
 }
+
+// marks published events whose end time has passed as completed
+func CompleteEndedEvents(ctx context.Context) (int64, error) {
+	query := `
+	UPDATE events
+	SET status = ?, version = version + 1
+	WHERE status = ? AND COALESCE(end_date_time, dateTime) < ? AND deleted_at IS NULL
+	RETURNING ` + eventColumns
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, EventStatusCompleted, EventStatusPublished, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	var completed []Event
+	for rows.Next() {
+		var event Event
+		if err := rows.Scan(event.scanFields()...); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		completed = append(completed, event)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, event := range completed {
+		if err := enqueueOutbox(ctx, tx, OutboxEventUpdated, event); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	for _, event := range completed {
+		publishEvent(LiveEventUpdated, event)
+	}
+	return int64(len(completed)), nil
+}
+
+// undoes a soft delete
+func RestoreEvent(ctx context.Context, id int) error {
+	query := `UPDATE events SET deleted_at = NULL, version = version + 1 WHERE id = ? AND deleted_at IS NOT NULL RETURNING ` + eventColumns
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var event Event
+	err = tx.QueryRowContext(ctx, query, id).Scan(event.scanFields()...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrDeletedEventNotFound
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			return timeoutError("restoring event")
+		}
+		return err
+	}
+
+	if err := enqueueOutbox(ctx, tx, OutboxEventUpdated, event); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	publishEvent(LiveEventUpdated, event)
+	return nil
+}
+
+// returns a page of soft-deleted events, most recently deleted first
+func GetDeletedEvents(ctx context.Context, page, limit int) ([]Event, int, error) {
+	var total int
+	countQuery := `SELECT COUNT(*) FROM events WHERE deleted_at IS NOT NULL`
+
+	err := db.ReadDB.QueryRowContext(ctx, countQuery).Scan(&total)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, 0, timeoutError("counting deleted events")
+		}
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + eventColumns + ` FROM events WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC LIMIT ? OFFSET ?`
+	rows, err := db.ReadDB.QueryContext(ctx, query, limit, (page-1)*limit)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, 0, timeoutError("fetching deleted events")
+		}
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	events := make([]Event, 0)
+	for rows.Next() {
+		var event Event
+		if err := rows.Scan(event.scanFields()...); err != nil {
+			return nil, 0, err
+		}
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
+
+// tables holding rows that reference events(id), cleared before an
+// event is hard-deleted so nothing is left dangling
+var eventDependentTables = []string{"registrations", "event_cohosts", "notifications", "event_revisions"}
+
+// hard-deletes events soft-deleted before cutoff along with their dependent rows
+func PurgeDeletedEvents(ctx context.Context, cutoff time.Time) (int64, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	expired := `SELECT id FROM events WHERE deleted_at IS NOT NULL AND deleted_at < ?`
+	for _, table := range eventDependentTables {
+		query := `DELETE FROM ` + table + ` WHERE event_id IN (` + expired + `)`
+		if _, err := tx.ExecContext(ctx, query, cutoff.UTC()); err != nil {
+			return 0, err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM events WHERE id IN (`+expired+`)`, cutoff.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, tx.Commit()
+}
+
+func utcOrNil(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
@@ -4,6 +4,7 @@ import (
 	"REST-API/config"
 	"REST-API/db"
 	"context"
+	"errors"
 	"os"
 	"testing"
 	"time"
@@ -17,8 +18,16 @@ func setupTestDB(t *testing.T) {
 	os.Setenv("JWT_SECRET", "test-secret-key")
 	os.Setenv("DB_PATH", ":memory:")
 
-	config.Load()
+	if err := config.Load(nil); err != nil {
+		t.Fatalf("could not load config: %v", err)
+	}
 	db.InitDB()
+
+	// events reference users(id), so tests that use UserID 1 need it to exist
+	_, err := db.DB.Exec(`INSERT INTO users(id, email, password) VALUES (1, 'owner@example.com', 'hashed')`)
+	if err != nil {
+		t.Fatalf("could not seed test user: %v", err)
+	}
 }
 
 func TestSaveEvent(t *testing.T) {
@@ -30,6 +39,7 @@ func TestSaveEvent(t *testing.T) {
 		Location:    "Test Location",
 		DateTime:    time.Now().Add(24 * time.Hour),
 		UserID:      1,
+		Status:      EventStatusPublished,
 	}
 
 	err := event.Save(context.Background())
@@ -53,6 +63,7 @@ func TestGetAllEvents(t *testing.T) {
 			Location:    "Test Location",
 			DateTime:    time.Now().Add(24 * time.Hour),
 			UserID:      1,
+			Status:      EventStatusPublished,
 		}
 		event.Save(context.Background())
 	}
@@ -78,6 +89,7 @@ func TestGetEventByID(t *testing.T) {
 		Location:    "Somewhere",
 		DateTime:    time.Now().Add(24 * time.Hour),
 		UserID:      1,
+		Status:      EventStatusPublished,
 	}
 	event.Save(context.Background())
 
@@ -114,6 +126,7 @@ func TestUpdateEvent(t *testing.T) {
 		Location:    "Original Location",
 		DateTime:    time.Now().Add(24 * time.Hour),
 		UserID:      1,
+		Status:      EventStatusPublished,
 	}
 	event.Save(context.Background())
 
@@ -130,6 +143,35 @@ func TestUpdateEvent(t *testing.T) {
 	}
 }
 
+func TestUpdateEvent_VersionConflict(t *testing.T) {
+	setupTestDB(t)
+
+	event := saveTestEvent(t)
+	stale := event
+
+	event.Name = "First Writer"
+	if err := event.Update(context.Background()); err != nil {
+		t.Fatalf("expected no error on update, got: %v", err)
+	}
+	if event.Version != 2 {
+		t.Errorf("expected version 2 after update, got %d", event.Version)
+	}
+
+	stale.Name = "Second Writer"
+	err := stale.Update(context.Background())
+	if !errors.Is(err, ErrPreconditionFailed) || err.Error() != "event was modified by someone else" {
+		t.Fatalf("expected a conflict for a stale version, got: %v", err)
+	}
+	if err := stale.Delete(context.Background()); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("expected deleting a stale version to fail, got: %v", err)
+	}
+
+	found, _ := GetEventByID(context.Background(), event.ID)
+	if found.Name != "First Writer" || found.Version != 2 {
+		t.Errorf("expected the first write to win, got %q at version %d", found.Name, found.Version)
+	}
+}
+
 func TestDeleteEvent(t *testing.T) {
 	setupTestDB(t)
 
@@ -139,6 +181,7 @@ func TestDeleteEvent(t *testing.T) {
 		Location:    "Nowhere",
 		DateTime:    time.Now().Add(24 * time.Hour),
 		UserID:      1,
+		Status:      EventStatusPublished,
 	}
 	event.Save(context.Background())
 
@@ -153,6 +196,48 @@ func TestDeleteEvent(t *testing.T) {
 	}
 }
 
+func TestRestoreAndPurgeDeletedEvents(t *testing.T) {
+	setupTestDB(t)
+
+	event := saveTestEvent(t)
+	if err := event.Delete(context.Background()); err != nil {
+		t.Fatalf("expected no error on delete, got: %v", err)
+	}
+
+	deleted, total, err := GetDeletedEvents(context.Background(), 1, 10)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if total != 1 || len(deleted) != 1 || deleted[0].DeletedAt == nil {
+		t.Fatalf("expected 1 deleted event with deletedAt set, got total %d", total)
+	}
+
+	if err := RestoreEvent(context.Background(), event.ID); err != nil {
+		t.Fatalf("expected no error on restore, got: %v", err)
+	}
+	found, _ := GetEventByID(context.Background(), event.ID)
+	if found == nil {
+		t.Fatal("expected restored event to be visible again")
+	}
+	if err := RestoreEvent(context.Background(), event.ID); err == nil {
+		t.Error("expected error restoring an event that isn't deleted")
+	}
+
+	// deleted events are only purged once they're older than the cutoff
+	found.Delete(context.Background())
+	purged, err := PurgeDeletedEvents(context.Background(), time.Now().Add(-time.Hour))
+	if err != nil || purged != 0 {
+		t.Fatalf("expected nothing purged before retention, got %d (%v)", purged, err)
+	}
+	purged, err = PurgeDeletedEvents(context.Background(), time.Now().Add(time.Minute))
+	if err != nil || purged != 1 {
+		t.Fatalf("expected 1 event purged, got %d (%v)", purged, err)
+	}
+	if err := RestoreEvent(context.Background(), event.ID); err == nil {
+		t.Error("expected purged event to be gone for good")
+	}
+}
+
 func TestPagination(t *testing.T) {
 	setupTestDB(t)
 
@@ -164,6 +249,7 @@ func TestPagination(t *testing.T) {
 			Location:    "Anywhere",
 			DateTime:    time.Now().Add(24 * time.Hour),
 			UserID:      1,
+			Status:      EventStatusPublished,
 		}
 		event.Save(context.Background())
 	}
@@ -183,3 +269,226 @@ func TestPagination(t *testing.T) {
 		t.Errorf("expected 2 events on page 2, got %d", len(events))
 	}
 }
+
+func TestSaveEventTx_Rollback(t *testing.T) {
+	setupTestDB(t)
+
+	tx, err := db.DB.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("could not begin transaction: %v", err)
+	}
+
+	event := Event{
+		Name:        "Imported Event",
+		Description: "Saved inside a caller-owned transaction",
+		Location:    "Anywhere",
+		DateTime:    time.Now().Add(24 * time.Hour),
+		UserID:      1,
+		Status:      EventStatusPublished,
+	}
+	if err := event.SaveTx(context.Background(), tx); err != nil {
+		t.Fatalf("expected no error saving in transaction, got: %v", err)
+	}
+	tx.Rollback()
+
+	found, _ := GetEventByID(context.Background(), event.ID)
+	if found != nil {
+		t.Error("expected rolled back event to not exist")
+	}
+}
+
+func TestSaveEvents_AllOrNothing(t *testing.T) {
+	setupTestDB(t)
+
+	valid := Event{
+		Name:        "Batch Event",
+		Description: "Saved together with the rest of the batch",
+		Location:    "Anywhere",
+		DateTime:    time.Now().Add(24 * time.Hour),
+		UserID:      1,
+	}
+	orphan := valid
+	orphan.UserID = 999 // no such user, violates the foreign key
+
+	err := SaveEvents(context.Background(), []Event{valid, orphan})
+	if err == nil {
+		t.Fatal("expected the batch to fail")
+	}
+	count := 0
+	ForEachEvent(context.Background(), func(Event) error { count++; return nil })
+	if count != 0 {
+		t.Errorf("expected no events after a failed batch, got %d", count)
+	}
+
+	events := []Event{valid, valid}
+	if err := SaveEvents(context.Background(), events); err != nil {
+		t.Fatalf("expected no error saving batch, got: %v", err)
+	}
+	if events[0].ID == 0 || events[1].ID == 0 {
+		t.Error("expected IDs to be set on the saved events")
+	}
+
+	var exported []int
+	ForEachEvent(context.Background(), func(e Event) error { exported = append(exported, e.ID); return nil })
+	if len(exported) != 2 || exported[0] != events[0].ID || events[0].Status != EventStatusDraft {
+		t.Errorf("expected both draft events to be listed in ID order, got %v", exported)
+	}
+}
+
+func TestGetEventsByUser(t *testing.T) {
+	setupTestDB(t)
+
+	other := User{Email: "other@example.com", Password: "secret123"}
+	other.Save(context.Background())
+
+	for _, userID := range []int{1, 1, other.ID} {
+		event := Event{
+			Name:        "Owned Event",
+			Description: "Testing events by owner",
+			Location:    "Anywhere",
+			DateTime:    time.Now().Add(24 * time.Hour),
+			UserID:      userID,
+			Status:      EventStatusPublished,
+		}
+		event.Save(context.Background())
+	}
+
+	events, total, err := GetEventsByUser(context.Background(), 1, TimeFilterUpcoming, 1, 10)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if total != 2 || len(events) != 2 {
+		t.Errorf("expected 2 events for user 1, got total %d and %d results", total, len(events))
+	}
+
+	_, total, _ = GetEventsByUser(context.Background(), 1, TimeFilterPast, 1, 10)
+	if total != 0 {
+		t.Errorf("expected no past events, got %d", total)
+	}
+}
+
+func TestEventLifecycle(t *testing.T) {
+	setupTestDB(t)
+
+	event := Event{
+		Name:        "Lifecycle Event",
+		Description: "Moves through every status",
+		Location:    "Anywhere",
+		DateTime:    time.Now().Add(24 * time.Hour),
+		UserID:      1,
+	}
+	event.Save(context.Background())
+	if event.Status != EventStatusDraft {
+		t.Errorf("expected new events to be drafts, got %s", event.Status)
+	}
+
+	registration := Registration{EventID: event.ID, UserID: 1}
+	if err := registration.Save(context.Background()); err == nil {
+		t.Error("expected registering for a draft to fail")
+	}
+
+	if err := event.Publish(context.Background()); err != nil {
+		t.Fatalf("expected no error publishing, got: %v", err)
+	}
+	if err := event.Publish(context.Background()); err == nil {
+		t.Error("expected publishing twice to fail")
+	}
+	if err := registration.Save(context.Background()); err != nil {
+		t.Fatalf("expected registering for a published event to work, got: %v", err)
+	}
+
+	if err := event.Cancel(context.Background(), "Venue unavailable"); err != nil {
+		t.Fatalf("expected no error cancelling, got: %v", err)
+	}
+
+	cancelled, _ := GetEventByID(context.Background(), event.ID)
+	if cancelled.Status != EventStatusCancelled || cancelled.CancelReason != "Venue unavailable" {
+		t.Errorf("expected cancelled event with reason, got %s %q", cancelled.Status, cancelled.CancelReason)
+	}
+
+	notifications, total, _ := GetUserNotifications(context.Background(), 1, true, 1, 10)
+	if total != 1 || notifications[0].Type != NotificationEventCancelled {
+		t.Errorf("expected one cancellation notification, got %v", notifications)
+	}
+}
+
+func TestCancelDraftEvent(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+
+	draft := Event{
+		Name:        "Draft Event",
+		Description: "Never published",
+		Location:    "Anywhere",
+		DateTime:    time.Now().Add(24 * time.Hour),
+		UserID:      1,
+	}
+	if err := draft.Save(ctx); err != nil {
+		t.Fatalf("could not save draft: %v", err)
+	}
+
+	if err := draft.Cancel(ctx, "Changed my mind"); !errors.Is(err, ErrEventNotPublished) {
+		t.Fatalf("expected ErrEventNotPublished cancelling a draft, got: %v", err)
+	}
+
+	stored, _ := GetEventByID(ctx, draft.ID)
+	if stored.Status != EventStatusDraft {
+		t.Errorf("expected the draft to stay a draft, got %s", stored.Status)
+	}
+	if _, total, _ := GetAllEvents(ctx, 1, 10); total != 0 {
+		t.Errorf("expected the draft to stay out of the public list, got %d events", total)
+	}
+}
+
+func TestCompleteEndedEvents(t *testing.T) {
+	setupTestDB(t)
+
+	ended := Event{
+		Name:        "Ended Event",
+		Description: "Already took place",
+		Location:    "Anywhere",
+		DateTime:    time.Now().Add(-2 * time.Hour),
+		UserID:      1,
+		Status:      EventStatusPublished,
+	}
+	ended.Save(context.Background())
+
+	upcoming := Event{
+		Name:        "Upcoming Event",
+		Description: "Still in the future",
+		Location:    "Anywhere",
+		DateTime:    time.Now().Add(24 * time.Hour),
+		UserID:      1,
+		Status:      EventStatusPublished,
+	}
+	upcoming.Save(context.Background())
+
+	completed, err := CompleteEndedEvents(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if completed != 1 {
+		t.Errorf("expected 1 event to be completed, got %d", completed)
+	}
+
+	found, _ := GetEventByID(context.Background(), ended.ID)
+	if found.Status != EventStatusCompleted {
+		t.Errorf("expected ended event to be completed, got %s", found.Status)
+	}
+}
+
+func TestGetEventByID_Timeout(t *testing.T) {
+	setupTestDB(t)
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	_, err := GetEventByID(ctx, 1)
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected ErrTimeout for an expired context, got: %v", err)
+	}
+	var modelErr *Error
+	if !errors.As(err, &modelErr) || modelErr.Code != "timeout" {
+		t.Errorf("expected a timeout error code, got: %v", err)
+	}
+}
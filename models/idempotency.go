@@ -0,0 +1,139 @@
+package models
+
+import (
+	"REST-API/db"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// a response stored under an Idempotency-Key so retries get the same answer.
+// Status is 0 while the first request is still being handled.
+type IdempotentResponse struct {
+	Key         string
+	UserID      int
+	RequestHash string
+	Status      int
+	Header      http.Header
+	Body        []byte
+	ExpiresAt   time.Time
+}
+
+// claims key for the user's request, returning nil if it's now theirs to
+// handle, or the existing record if the key was already used and hasn't expired
+func ClaimIdempotencyKey(ctx context.Context, userID int, key, requestHash string, ttl time.Duration) (*IdempotentResponse, error) {
+	now := time.Now().UTC()
+
+	// an expired key is taken over as if it had never been used
+	query := `
+	INSERT INTO idempotency_keys(user_id, key, request_hash, status, created_at, expires_at)
+	VALUES (?, ?, ?, 0, ?, ?)
+	ON CONFLICT(user_id, key) DO UPDATE SET
+		request_hash = excluded.request_hash,
+		status = 0,
+		headers = NULL,
+		body = NULL,
+		created_at = excluded.created_at,
+		expires_at = excluded.expires_at
+	WHERE idempotency_keys.expires_at <= excluded.created_at
+	`
+
+	result, err := db.DB.ExecContext(ctx, query, userID, key, requestHash, now, now.Add(ttl))
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, timeoutError("claiming idempotency key")
+		}
+		return nil, err
+	}
+
+	claimed, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if claimed > 0 {
+		return nil, nil
+	}
+
+	return getIdempotentResponse(ctx, userID, key)
+}
+
+func getIdempotentResponse(ctx context.Context, userID int, key string) (*IdempotentResponse, error) {
+	query := `
+	SELECT key, user_id, request_hash, status, headers, body, expires_at
+	FROM idempotency_keys
+	WHERE user_id = ? AND key = ?
+	`
+
+	var response IdempotentResponse
+	var headers sql.NullString
+	err := db.ReadDB.QueryRowContext(ctx, query, userID, key).Scan(
+		&response.Key,
+		&response.UserID,
+		&response.RequestHash,
+		&response.Status,
+		&headers,
+		&response.Body,
+		&response.ExpiresAt,
+	)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, timeoutError("fetching idempotency key")
+		}
+		return nil, err
+	}
+
+	if headers.Valid {
+		if err := json.Unmarshal([]byte(headers.String), &response.Header); err != nil {
+			return nil, err
+		}
+	}
+
+	return &response, nil
+}
+
+// stores the response to replay for later requests with the same key
+func (r *IdempotentResponse) Save(ctx context.Context) error {
+	headers, err := json.Marshal(r.Header)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE idempotency_keys SET status = ?, headers = ?, body = ? WHERE user_id = ? AND key = ?`
+
+	_, err = db.DB.ExecContext(ctx, query, r.Status, string(headers), r.Body, r.UserID, r.Key)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return timeoutError("saving idempotent response")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// gives up a claimed key so the request can be retried, e.g. after a server error
+func ReleaseIdempotencyKey(ctx context.Context, userID int, key string) error {
+	query := `DELETE FROM idempotency_keys WHERE user_id = ? AND key = ? AND status = 0`
+
+	_, err := db.DB.ExecContext(ctx, query, userID, key)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return timeoutError("releasing idempotency key")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// removes keys whose TTL has passed
+func PurgeExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	result, err := db.DB.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= ?`, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
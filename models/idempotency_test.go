@@ -0,0 +1,61 @@
+package models
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyKeys(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+
+	existing, err := ClaimIdempotencyKey(ctx, 1, "key-1", "hash-a", time.Hour)
+	if err != nil || existing != nil {
+		t.Fatalf("expected first claim to succeed, got %v (%v)", existing, err)
+	}
+
+	// a second claim sees the in-progress request
+	existing, err = ClaimIdempotencyKey(ctx, 1, "key-1", "hash-a", time.Hour)
+	if err != nil || existing == nil || existing.Status != 0 {
+		t.Fatalf("expected an in-progress record, got %+v (%v)", existing, err)
+	}
+
+	response := IdempotentResponse{
+		Key:    "key-1",
+		UserID: 1,
+		Status: http.StatusCreated,
+		Header: http.Header{"Content-Type": {"application/json"}},
+		Body:   []byte(`{"id":1}`),
+	}
+	if err := response.Save(ctx); err != nil {
+		t.Fatalf("expected no error saving response, got: %v", err)
+	}
+
+	existing, err = ClaimIdempotencyKey(ctx, 1, "key-1", "hash-b", time.Hour)
+	if err != nil || existing == nil {
+		t.Fatalf("expected the stored response, got %v (%v)", existing, err)
+	}
+	if existing.Status != http.StatusCreated || string(existing.Body) != `{"id":1}` ||
+		existing.Header.Get("Content-Type") != "application/json" || existing.RequestHash != "hash-a" {
+		t.Errorf("expected the original response to be replayed, got %+v", existing)
+	}
+
+	// a released key can be claimed again, a completed one can't be released
+	if err := ReleaseIdempotencyKey(ctx, 1, "key-1"); err != nil {
+		t.Fatalf("expected no error releasing key, got: %v", err)
+	}
+	if existing, _ := ClaimIdempotencyKey(ctx, 1, "key-1", "hash-a", time.Hour); existing == nil {
+		t.Error("expected a completed key to survive release")
+	}
+
+	// expired keys are taken over by the next request
+	if _, err := ClaimIdempotencyKey(ctx, 1, "key-2", "hash-a", -time.Second); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	existing, err = ClaimIdempotencyKey(ctx, 1, "key-2", "hash-b", time.Hour)
+	if err != nil || existing != nil {
+		t.Errorf("expected an expired key to be claimable, got %+v (%v)", existing, err)
+	}
+}
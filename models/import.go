@@ -0,0 +1,190 @@
+package models
+
+import (
+	"REST-API/utils"
+	"encoding/csv"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// columns an import CSV must have, matched case-insensitively
+var importCSVColumns = []string{"name", "description", "location", "datetime"}
+
+type importRow struct {
+	Row    int
+	Event  Event
+	Errors []utils.ValidationError
+}
+
+type ImportRowError struct {
+	Row    int                     `json:"row"`
+	Errors []utils.ValidationError `json:"errors"`
+}
+
+var ErrUnsupportedImportFormat = errors.New("unsupported file format, expected .csv or .ics")
+
+// parses a CSV or iCalendar import, picked by format or else the file
+// extension, and validates every event as owned by ownerID. One event is
+// returned per row, and none may be saved if any row has errors. Shared by
+// POST /events/import and the import command.
+func ParseImport(r io.Reader, filename, format string, ownerID int) ([]Event, []ImportRowError, error) {
+	var rows []importRow
+	var err error
+	switch importFormat(filename, format) {
+	case "csv":
+		rows, err = parseImportCSV(r)
+	case "ics":
+		rows, err = parseImportICS(r)
+	default:
+		return nil, nil, ErrUnsupportedImportFormat
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rowErrors := make([]ImportRowError, 0)
+	events := make([]Event, 0, len(rows))
+	for i := range rows {
+		rows[i].Event.UserID = ownerID
+		rows[i].Errors = mergeImportErrors(rows[i].Errors, utils.ValidateStruct(rows[i].Event))
+		if rows[i].Errors != nil {
+			rowErrors = append(rowErrors, ImportRowError{Row: rows[i].Row, Errors: rows[i].Errors})
+		}
+		events = append(events, rows[i].Event)
+	}
+
+	return events, rowErrors, nil
+}
+
+// combines parse errors with validation errors, dropping validation
+// errors for fields that already failed to parse
+func mergeImportErrors(parseErrors, validationErrors []utils.ValidationError) []utils.ValidationError {
+	merged := parseErrors
+	for _, validationError := range validationErrors {
+		duplicate := false
+		for _, parseError := range parseErrors {
+			if parseError.Field == validationError.Field {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			merged = append(merged, validationError)
+		}
+	}
+	return merged
+}
+
+// picks the parser from an explicit ?format= or the file extension
+func importFormat(filename, format string) string {
+	if format != "" {
+		return strings.ToLower(format)
+	}
+	return strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+}
+
+func parseImportCSV(r io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("could not read CSV header")
+	}
+
+	columns := make(map[string]int)
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	for _, required := range importCSVColumns {
+		if _, ok := columns[required]; !ok {
+			return nil, errors.New("CSV is missing required column: " + required)
+		}
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.New("could not parse CSV: " + err.Error())
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := importRow{
+			Row: line,
+			Event: Event{
+				Name:        record[columns["name"]],
+				Description: record[columns["description"]],
+				Location:    record[columns["location"]],
+			},
+		}
+
+		dateTime, err := time.Parse(time.RFC3339, strings.TrimSpace(record[columns["datetime"]]))
+		if err != nil {
+			row.Errors = []utils.ValidationError{{
+				Field:   "datetime",
+				Message: "dateTime must be an RFC 3339 timestamp",
+			}}
+		}
+		row.Event.DateTime = dateTime
+
+		// endDateTime is an optional column
+		if index, ok := columns["enddatetime"]; ok && strings.TrimSpace(record[index]) != "" {
+			endDateTime, err := time.Parse(time.RFC3339, strings.TrimSpace(record[index]))
+			if err != nil {
+				row.Errors = append(row.Errors, utils.ValidationError{
+					Field:   "enddatetime",
+					Message: "endDateTime must be an RFC 3339 timestamp",
+				})
+			} else {
+				row.Event.EndDateTime = &endDateTime
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("no events found in CSV")
+	}
+	return rows, nil
+}
+
+func parseImportICS(r io.Reader) ([]importRow, error) {
+	parsed, err := utils.ParseICalendar(r)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]importRow, 0, len(parsed))
+	for i, calendarEvent := range parsed {
+		row := importRow{
+			Row: i + 1, // VEVENT position within the file
+			Event: Event{
+				Name:        calendarEvent.Summary,
+				Description: calendarEvent.Description,
+				Location:    calendarEvent.Location,
+				DateTime:    calendarEvent.Start,
+			},
+		}
+		if !calendarEvent.End.IsZero() {
+			end := calendarEvent.End
+			row.Event.EndDateTime = &end
+		}
+		if calendarEvent.Err != nil {
+			row.Errors = []utils.ValidationError{{
+				Field:   "datetime",
+				Message: calendarEvent.Err.Error(),
+			}}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"REST-API/utils"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseImport_CSV(t *testing.T) {
+	utils.RegisterCustomValidations()
+
+	start := time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+	file := "name,description,location,dateTime\n" +
+		"Imported Event,Read from a CSV file,Anywhere," + start + "\n" +
+		"Bad Event,Has no usable start time,Anywhere,tomorrow\n"
+
+	events, rowErrors, err := ParseImport(strings.NewReader(file), "events.csv", "", 1)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(events) != 2 || events[0].Name != "Imported Event" || events[0].UserID != 1 {
+		t.Errorf("expected both rows owned by user 1, got %+v", events)
+	}
+	if len(rowErrors) != 1 || rowErrors[0].Row != 3 {
+		t.Errorf("expected an error for row 3, got %+v", rowErrors)
+	}
+}
+
+func TestParseImport_UnsupportedFormat(t *testing.T) {
+	_, _, err := ParseImport(strings.NewReader(""), "events.xlsx", "", 1)
+	if !errors.Is(err, ErrUnsupportedImportFormat) {
+		t.Errorf("expected ErrUnsupportedImportFormat, got: %v", err)
+	}
+}
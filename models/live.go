@@ -0,0 +1,41 @@
+package models
+
+import (
+	"REST-API/db"
+	"REST-API/live"
+	"context"
+)
+
+// message types pushed to event streams, sent once the change has committed.
+// Event changes carry the event, registration changes the new count.
+const (
+	LiveEventCreated   = OutboxEventCreated
+	LiveEventUpdated   = OutboxEventUpdated
+	LiveEventCancelled = OutboxEventCancelled
+	LiveEventDeleted   = OutboxEventDeleted
+	LiveRegistrations  = "registrations"
+)
+
+type RegistrationCount struct {
+	EventID       int `json:"eventId"`
+	Registrations int `json:"registrations"`
+}
+
+func publishEvent(messageType string, event Event) {
+	live.Publish(messageType, event.ID, event.Status != EventStatusDraft, event)
+}
+
+func publishEventDeleted(event Event) {
+	live.Publish(LiveEventDeleted, event.ID, event.Status != EventStatusDraft, map[string]int{"id": event.ID})
+}
+
+// drafts can't be registered for, so counts are always public
+func publishRegistrationCount(eventID, count int) {
+	live.Publish(LiveRegistrations, eventID, true, RegistrationCount{EventID: eventID, Registrations: count})
+}
+
+func countRegistrations(ctx context.Context, exec db.Executor, eventID int) (int, error) {
+	var count int
+	err := exec.QueryRowContext(ctx, `SELECT COUNT(*) FROM registrations WHERE event_id = ?`, eventID).Scan(&count)
+	return count, err
+}
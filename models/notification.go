@@ -0,0 +1,120 @@
+package models
+
+import (
+	"REST-API/db"
+	"context"
+	"time"
+)
+
+const NotificationEventCancelled = "event.cancelled"
+
+type Notification struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"userId"`
+	EventID   *int       `json:"eventId,omitempty"`
+	Type      string     `json:"type"`
+	Message   string     `json:"message"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ReadAt    *time.Time `json:"readAt,omitempty"`
+}
+
+// creates one notification for every user registered for the event
+func notifyRegistrants(ctx context.Context, exec db.Executor, eventID int, notificationType, message string) error {
+	query := `
+	INSERT INTO notifications(user_id, event_id, type, message, created_at)
+	SELECT user_id, event_id, ?, ?, ?
+	FROM registrations
+	WHERE event_id = ?
+	`
+
+	_, err := exec.ExecContext(ctx, query, notificationType, message, time.Now().UTC(), eventID)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return timeoutError("notifying registrants")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// returns a page of the user's notifications, newest first
+func GetUserNotifications(ctx context.Context, userID int, unreadOnly bool, page, limit int) ([]Notification, int, error) {
+	condition := ""
+	if unreadOnly {
+		condition = " AND read_at IS NULL"
+	}
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM notifications WHERE user_id = ?` + condition
+
+	err := db.ReadDB.QueryRowContext(ctx, countQuery, userID).Scan(&total)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, 0, timeoutError("counting notifications")
+		}
+		return nil, 0, err
+	}
+
+	query := `
+	SELECT id, user_id, event_id, type, message, created_at, read_at
+	FROM notifications
+	WHERE user_id = ?` + condition + `
+	ORDER BY created_at DESC, id DESC
+	LIMIT ? OFFSET ?
+	`
+	rows, err := db.ReadDB.QueryContext(ctx, query, userID, limit, (page-1)*limit)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, 0, timeoutError("fetching notifications")
+		}
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	notifications := make([]Notification, 0)
+	for rows.Next() {
+		var notification Notification
+		err := rows.Scan(
+			&notification.ID,
+			&notification.UserID,
+			&notification.EventID,
+			&notification.Type,
+			&notification.Message,
+			&notification.CreatedAt,
+			&notification.ReadAt,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		notifications = append(notifications, notification)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return notifications, total, nil
+}
+
+func MarkNotificationRead(ctx context.Context, id, userID int) error {
+	query := `UPDATE notifications SET read_at = COALESCE(read_at, ?) WHERE id = ? AND user_id = ?`
+
+	result, err := db.DB.ExecContext(ctx, query, time.Now().UTC(), id, userID)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return timeoutError("updating notification")
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotificationNotFound
+	}
+
+	return nil
+}
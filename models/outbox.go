@@ -0,0 +1,262 @@
+package models
+
+import (
+	"REST-API/db"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// outbox topics, named like the audit actions they accompany
+const (
+	OutboxEventCreated          = "event.created"
+	OutboxEventUpdated          = "event.updated"
+	OutboxEventCancelled        = "event.cancelled"
+	OutboxEventDeleted          = "event.deleted"
+	OutboxRegistrationCreated   = "registration.created"
+	OutboxRegistrationCancelled = "registration.cancelled"
+)
+
+// where a message is in its delivery
+const (
+	OutboxStatusPending    = "pending"
+	OutboxStatusProcessing = "processing"
+	OutboxStatusDone       = "done"
+	OutboxStatusDead       = "dead" // ran out of attempts, waits for an admin to retry it
+)
+
+// a side effect recorded in the same transaction as the change that caused
+// it, so it is carried out once that change commits even if the process
+// crashes before it gets the chance
+type OutboxMessage struct {
+	ID          int             `json:"id"`
+	Topic       string          `json:"topic"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	AvailableAt time.Time       `json:"availableAt"`
+	LastError   string          `json:"lastError,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	ProcessedAt *time.Time      `json:"processedAt,omitempty"`
+}
+
+const outboxColumns = `id, topic, payload, status, attempts, available_at, last_error, created_at, processed_at`
+
+func (m *OutboxMessage) scanFields() []any {
+	// the driver only scans TEXT into a plain []byte
+	return []any{&m.ID, &m.Topic, (*[]byte)(&m.Payload), &m.Status, &m.Attempts, &m.AvailableAt, &m.LastError, &m.CreatedAt, &m.ProcessedAt}
+}
+
+// records a message to be delivered once the caller's transaction commits
+func enqueueOutbox(ctx context.Context, exec db.Executor, topic string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO outbox(topic, payload, status, available_at, created_at)
+	VALUES (?, ?, ?, ?, ?)
+	`
+
+	now := time.Now().UTC()
+	_, err = exec.ExecContext(ctx, query, topic, string(body), OutboxStatusPending, now, now)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return timeoutError("queueing side effects")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// records a message outside of any other change, for side effects that
+// queue further work of their own
+func EnqueueOutbox(ctx context.Context, topic string, payload any) error {
+	return enqueueOutbox(ctx, db.DB, topic, payload)
+}
+
+// takes up to limit messages that are due and marks them as processing
+// until lease has passed. A message whose worker died mid-delivery is
+// picked up again once its lease runs out. Due messages that have already
+// had maxAttempts attempts are dead-lettered instead and returned as dead,
+// so one whose worker keeps crashing or hanging doesn't go round forever.
+func ClaimOutboxMessages(ctx context.Context, limit int, lease time.Duration, maxAttempts int) (claimed, dead []OutboxMessage, err error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	deadQuery := `
+	UPDATE outbox
+	SET status = ?, available_at = ?, locked_until = NULL,
+		last_error = CASE WHEN status = ? THEN 'lease expired on the last attempt' ELSE last_error END
+	WHERE attempts >= ? AND ((status = ? AND available_at <= ?) OR (status = ? AND locked_until <= ?))
+	RETURNING ` + outboxColumns
+
+	dead, err = queryOutboxMessages(ctx, tx, deadQuery,
+		OutboxStatusDead, now, OutboxStatusProcessing,
+		maxAttempts, OutboxStatusPending, now, OutboxStatusProcessing, now,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	claimQuery := `
+	UPDATE outbox
+	SET status = ?, attempts = attempts + 1, locked_until = ?
+	WHERE id IN (
+		SELECT id FROM outbox
+		WHERE (status = ? AND available_at <= ?) OR (status = ? AND locked_until <= ?)
+		ORDER BY available_at, id
+		LIMIT ?
+	)
+	RETURNING ` + outboxColumns
+
+	claimed, err = queryOutboxMessages(ctx, tx, claimQuery,
+		OutboxStatusProcessing, now.Add(lease),
+		OutboxStatusPending, now, OutboxStatusProcessing, now,
+		limit,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return claimed, dead, nil
+}
+
+func queryOutboxMessages(ctx context.Context, exec db.Executor, query string, args ...any) ([]OutboxMessage, error) {
+	rows, err := exec.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := make([]OutboxMessage, 0)
+	for rows.Next() {
+		var message OutboxMessage
+		if err := rows.Scan(message.scanFields()...); err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, rows.Err()
+}
+
+// marks a claimed message as delivered
+func CompleteOutboxMessage(ctx context.Context, id int) error {
+	query := `UPDATE outbox SET status = ?, processed_at = ?, locked_until = NULL, last_error = '' WHERE id = ?`
+
+	_, err := db.DB.ExecContext(ctx, query, OutboxStatusDone, time.Now().UTC(), id)
+	return err
+}
+
+// puts a claimed message back to be tried again at retryAt, or dead-letters
+// it when retryAt is zero
+func FailOutboxMessage(ctx context.Context, id int, reason string, retryAt time.Time) error {
+	status, availableAt := OutboxStatusPending, retryAt.UTC()
+	if retryAt.IsZero() {
+		status, availableAt = OutboxStatusDead, time.Now().UTC()
+	}
+
+	query := `UPDATE outbox SET status = ?, available_at = ?, locked_until = NULL, last_error = ? WHERE id = ?`
+
+	_, err := db.DB.ExecContext(ctx, query, status, availableAt, reason, id)
+	return err
+}
+
+// puts back a claimed message whose delivery was interrupted by shutdown,
+// due straight away and without using up an attempt
+func ReleaseOutboxMessage(ctx context.Context, id int) error {
+	query := `UPDATE outbox SET status = ?, attempts = attempts - 1, available_at = ?, locked_until = NULL WHERE id = ? AND status = ?`
+
+	_, err := db.DB.ExecContext(ctx, query, OutboxStatusPending, time.Now().UTC(), id, OutboxStatusProcessing)
+	return err
+}
+
+// returns a page of messages in the given status, newest first
+func GetOutboxMessages(ctx context.Context, status string, page, limit int) ([]OutboxMessage, int, error) {
+	var total int
+	countQuery := `SELECT COUNT(*) FROM outbox WHERE status = ?`
+
+	err := db.ReadDB.QueryRowContext(ctx, countQuery, status).Scan(&total)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, 0, timeoutError("counting outbox messages")
+		}
+		return nil, 0, err
+	}
+
+	query := `
+	SELECT ` + outboxColumns + `
+	FROM outbox
+	WHERE status = ?
+	ORDER BY id DESC
+	LIMIT ? OFFSET ?
+	`
+	rows, err := db.ReadDB.QueryContext(ctx, query, status, limit, (page-1)*limit)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, 0, timeoutError("fetching outbox messages")
+		}
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	messages := make([]OutboxMessage, 0)
+	for rows.Next() {
+		var message OutboxMessage
+		if err := rows.Scan(message.scanFields()...); err != nil {
+			return nil, 0, err
+		}
+		messages = append(messages, message)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return messages, total, nil
+}
+
+// gives a dead-lettered message a fresh set of attempts, starting now
+func RetryOutboxMessage(ctx context.Context, id int) (*OutboxMessage, error) {
+	query := `
+	UPDATE outbox SET status = ?, attempts = 0, available_at = ?
+	WHERE id = ? AND status = ?
+	RETURNING ` + outboxColumns
+
+	var message OutboxMessage
+	err := db.DB.QueryRowContext(ctx, query, OutboxStatusPending, time.Now().UTC(), id, OutboxStatusDead).Scan(message.scanFields()...)
+	if err == sql.ErrNoRows {
+		return nil, ErrDeadOutboxMessageNotFound
+	}
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, timeoutError("retrying outbox message")
+		}
+		return nil, err
+	}
+
+	return &message, nil
+}
+
+// deletes delivered messages processed before cutoff. Dead ones are kept
+// until an admin retries them.
+func PurgeProcessedOutboxMessages(ctx context.Context, cutoff time.Time) (int64, error) {
+	query := `DELETE FROM outbox WHERE status = ? AND processed_at < ?`
+
+	result, err := db.DB.ExecContext(ctx, query, OutboxStatusDone, cutoff.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,205 @@
+package models
+
+import (
+	"REST-API/db"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOutbox_WrittenWithDomainChanges(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+
+	event := saveTestEvent(t)
+	registration := Registration{EventID: event.ID, UserID: 1}
+	if err := registration.Save(ctx); err != nil {
+		t.Fatalf("could not register: %v", err)
+	}
+	if err := event.Cancel(ctx, "venue flooded"); err != nil {
+		t.Fatalf("could not cancel event: %v", err)
+	}
+
+	messages, _, err := GetOutboxMessages(ctx, OutboxStatusPending, 1, 10)
+	if err != nil {
+		t.Fatalf("could not list outbox: %v", err)
+	}
+	topics := []string{}
+	for _, message := range messages {
+		topics = append([]string{message.Topic}, topics...)
+	}
+	want := []string{OutboxEventCreated, OutboxRegistrationCreated, OutboxEventCancelled}
+	if len(topics) != len(want) || topics[0] != want[0] || topics[1] != want[1] || topics[2] != want[2] {
+		t.Fatalf("expected %v queued in order, got %v", want, topics)
+	}
+
+	var cancelled Event
+	json.Unmarshal(messages[0].Payload, &cancelled)
+	if cancelled.ID != event.ID || cancelled.Status != EventStatusCancelled || cancelled.CancelReason != "venue flooded" {
+		t.Errorf("expected the cancelled event as payload, got %s", messages[0].Payload)
+	}
+}
+
+func TestOutbox_RolledBackWithDomainChanges(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+
+	tx, _ := db.DB.BeginTx(ctx, nil)
+	event := Event{Name: "Never", Description: "Rolled back", Location: "Nowhere", DateTime: time.Now().Add(time.Hour), UserID: 1}
+	if err := event.SaveTx(ctx, tx); err != nil {
+		t.Fatalf("expected no error saving in transaction, got: %v", err)
+	}
+	tx.Rollback()
+
+	_, total, _ := GetOutboxMessages(ctx, OutboxStatusPending, 1, 10)
+	if total != 0 {
+		t.Errorf("expected no message for a rolled back event, got %d", total)
+	}
+}
+
+func TestOutbox_DeletingQueuesEventDeleted(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+
+	event := saveTestEvent(t)
+	registration := Registration{EventID: event.ID, UserID: 1}
+	registration.Save(ctx)
+	feed, _ := GetOrCreateCalendarFeed(ctx, 1)
+	if err := event.Delete(ctx); err != nil {
+		t.Fatalf("could not delete event: %v", err)
+	}
+
+	messages, _, _ := GetOutboxMessages(ctx, OutboxStatusPending, 1, 1)
+	var deleted Event
+	json.Unmarshal(messages[0].Payload, &deleted)
+	if messages[0].Topic != OutboxEventDeleted || deleted.ID != event.ID || deleted.DeletedAt == nil || deleted.Version != event.Version+1 {
+		t.Errorf("expected event.deleted with the deleted event, got %s %+v", messages[0].Topic, deleted)
+	}
+
+	touched, _ := GetCalendarFeedByToken(ctx, feed.Token)
+	if !touched.UpdatedAt.After(feed.UpdatedAt) {
+		t.Errorf("expected the registrant's feed to be touched, still %v", touched.UpdatedAt)
+	}
+}
+
+func TestOutbox_ClaimRetryAndDeadLetter(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+
+	EnqueueOutbox(ctx, "test.topic", map[string]int{"n": 1})
+
+	claimed, _, err := ClaimOutboxMessages(ctx, 10, time.Minute, 3)
+	if err != nil || len(claimed) != 1 || claimed[0].Attempts != 1 || claimed[0].Status != OutboxStatusProcessing {
+		t.Fatalf("expected one message claimed on its first attempt, got %+v (%v)", claimed, err)
+	}
+	if again, _, _ := ClaimOutboxMessages(ctx, 10, time.Minute, 3); len(again) != 0 {
+		t.Error("expected a claimed message not to be claimed twice while its lease holds")
+	}
+
+	// retried later, so it isn't due yet
+	FailOutboxMessage(ctx, claimed[0].ID, "smtp down", time.Now().Add(time.Hour))
+	if due, _, _ := ClaimOutboxMessages(ctx, 10, time.Minute, 3); len(due) != 0 {
+		t.Error("expected a backed off message not to be due")
+	}
+
+	if _, err := RetryOutboxMessage(ctx, claimed[0].ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected only dead messages to be retryable, got: %v", err)
+	}
+
+	FailOutboxMessage(ctx, claimed[0].ID, "smtp still down", time.Time{})
+	dead, total, _ := GetOutboxMessages(ctx, OutboxStatusDead, 1, 10)
+	if total != 1 || dead[0].LastError != "smtp still down" {
+		t.Fatalf("expected the message to be dead-lettered with its error, got %+v", dead)
+	}
+
+	retried, err := RetryOutboxMessage(ctx, claimed[0].ID)
+	if err != nil || retried.Status != OutboxStatusPending || retried.Attempts != 0 {
+		t.Fatalf("expected a fresh pending message, got %+v (%v)", retried, err)
+	}
+	claimed, _, _ = ClaimOutboxMessages(ctx, 10, time.Minute, 3)
+	if len(claimed) != 1 {
+		t.Fatal("expected the retried message to be due straight away")
+	}
+
+	CompleteOutboxMessage(ctx, claimed[0].ID)
+	purged, err := PurgeProcessedOutboxMessages(ctx, time.Now().Add(time.Minute))
+	if err != nil || purged != 1 {
+		t.Errorf("expected the delivered message to be purged, got %d (%v)", purged, err)
+	}
+}
+
+func TestOutbox_ExpiredLeaseIsClaimedAgain(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+
+	EnqueueOutbox(ctx, "test.topic", nil)
+	first, _, _ := ClaimOutboxMessages(ctx, 1, -time.Second, 3) // a worker that died straight away
+
+	second, _, err := ClaimOutboxMessages(ctx, 1, time.Minute, 3)
+	if err != nil || len(second) != 1 || second[0].ID != first[0].ID || second[0].Attempts != 2 {
+		t.Errorf("expected the abandoned message to be claimed again, got %+v (%v)", second, err)
+	}
+}
+
+func TestOutbox_ExpiredLeaseOnLastAttemptIsDeadLettered(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+
+	EnqueueOutbox(ctx, "test.topic", nil)
+	ClaimOutboxMessages(ctx, 1, -time.Second, 2)
+	ClaimOutboxMessages(ctx, 1, -time.Second, 2) // crashed on both attempts
+
+	claimed, dead, err := ClaimOutboxMessages(ctx, 1, time.Minute, 2)
+	if err != nil || len(claimed) != 0 {
+		t.Fatalf("expected nothing claimed, got %+v (%v)", claimed, err)
+	}
+	if len(dead) != 1 || dead[0].Status != OutboxStatusDead || dead[0].Attempts != 2 || dead[0].LastError == "" {
+		t.Errorf("expected the message dead-lettered after its second attempt, got %+v", dead)
+	}
+}
+
+func TestOutbox_ReleaseDoesNotUseUpAnAttempt(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+
+	EnqueueOutbox(ctx, "test.topic", nil)
+	first, _, _ := ClaimOutboxMessages(ctx, 1, time.Minute, 3)
+	if err := ReleaseOutboxMessage(ctx, first[0].ID); err != nil {
+		t.Fatalf("could not release message: %v", err)
+	}
+
+	second, _, _ := ClaimOutboxMessages(ctx, 1, time.Minute, 3)
+	if len(second) != 1 || second[0].Attempts != 1 {
+		t.Errorf("expected the released message claimed again on its first attempt, got %+v", second)
+	}
+}
+
+func TestOutbox_CompletingAndRestoringQueueUpdates(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+
+	ended := Event{Name: "Ended", Description: "Already over", Location: "Here", DateTime: time.Now().Add(-time.Hour), UserID: 1, Status: EventStatusPublished}
+	ended.Save(ctx)
+	deleted := saveTestEvent(t)
+	deleted.Delete(ctx)
+
+	if completed, err := CompleteEndedEvents(ctx); err != nil || completed != 1 {
+		t.Fatalf("expected one event completed, got %d, %v", completed, err)
+	}
+	if err := RestoreEvent(ctx, deleted.ID); err != nil {
+		t.Fatalf("could not restore event: %v", err)
+	}
+
+	messages, _, _ := GetOutboxMessages(ctx, OutboxStatusPending, 1, 2)
+	var restored, completed Event
+	json.Unmarshal(messages[0].Payload, &restored)
+	json.Unmarshal(messages[1].Payload, &completed)
+	if messages[0].Topic != OutboxEventUpdated || restored.ID != deleted.ID || restored.DeletedAt != nil {
+		t.Errorf("expected event.updated for the restored event, got %s %s", messages[0].Topic, messages[0].Payload)
+	}
+	if messages[1].Topic != OutboxEventUpdated || completed.ID != ended.ID || completed.Status != EventStatusCompleted {
+		t.Errorf("expected event.updated for the completed event, got %s %s", messages[1].Topic, messages[1].Payload)
+	}
+}
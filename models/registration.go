@@ -3,14 +3,35 @@ package models
 import (
 	"REST-API/db"
 	"context"
-	"errors"
+	"database/sql"
 	"strings"
+	"time"
 )
 
 type Registration struct {
-	ID      int `json:"id"`
-	EventID int `json:"eventId"`
-	UserID  int `json:"userId"`
+	ID           int       `json:"id"`
+	EventID      int       `json:"eventId"`
+	UserID       int       `json:"userId"`
+	RegisteredAt time.Time `json:"registeredAt"`
+}
+
+// the only state a stored registration can be in, cancelling deletes it
+const RegistrationStatusRegistered = "registered"
+
+// a registration joined with its event, as seen by the registered user
+type UserRegistration struct {
+	RegistrationID int       `json:"registrationId"`
+	Status         string    `json:"status"`
+	RegisteredAt   time.Time `json:"registeredAt"`
+	Event          Event     `json:"event"`
+}
+
+// a registered user as seen by the event's organizers
+type Attendee struct {
+	RegistrationID int       `json:"registrationId"`
+	UserID         int       `json:"userId"`
+	Email          string    `json:"email"`
+	RegisteredAt   time.Time `json:"registeredAt"`
 }
 
 func (r *Registration) Save(ctx context.Context) error {
@@ -19,7 +40,10 @@ func (r *Registration) Save(ctx context.Context) error {
 		return err
 	}
 	if event == nil {
-		return errors.New("event not found")
+		return ErrEventNotFound
+	}
+	if !event.IsOpenForRegistration() {
+		return ErrRegistrationClosed
 	}
 
 	alreadyRegistered, err := IsUserRegistered(ctx, r.EventID, r.UserID)
@@ -27,21 +51,28 @@ func (r *Registration) Save(ctx context.Context) error {
 		return err
 	}
 	if alreadyRegistered {
-		return errors.New("already registered for this event")
+		return ErrAlreadyRegistered
 	}
 
-	query := `INSERT INTO registrations(event_id, user_id) VALUES (?, ?)`
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	result, err := db.DB.ExecContext(ctx, query, r.EventID, r.UserID)
+	query := `INSERT INTO registrations(event_id, user_id, registered_at) VALUES (?, ?, ?)`
+
+	registeredAt := time.Now().UTC()
+	result, err := tx.ExecContext(ctx, query, r.EventID, r.UserID, registeredAt)
 	if err != nil {
 		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
-			return errors.New("already registered for this event")
+			return ErrAlreadyRegistered
 		}
 		if ctx.Err() == context.DeadlineExceeded {
-			return errors.New("request timeout while registering for event")
+			return timeoutError("registering for event")
 		}
 		if ctx.Err() == context.Canceled {
-			return errors.New("request was canceled while registering for event")
+			return canceledError("registering for event")
 		}
 		return err
 	}
@@ -52,6 +83,23 @@ func (r *Registration) Save(ctx context.Context) error {
 	}
 
 	r.ID = int(id)
+	r.RegisteredAt = registeredAt
+	if err := enqueueOutbox(ctx, tx, OutboxRegistrationCreated, r); err != nil {
+		return err
+	}
+	if err := touchCalendarFeed(ctx, tx, r.UserID); err != nil {
+		return err
+	}
+	count, err := countRegistrations(ctx, tx, r.EventID)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	publishRegistrationCount(r.EventID, count)
 	return nil
 }
 
@@ -61,7 +109,7 @@ func (r *Registration) Cancel(ctx context.Context) error {
 		return err
 	}
 	if event == nil {
-		return errors.New("event not found")
+		return ErrEventNotFound
 	}
 
 	alreadyRegistered, err := IsUserRegistered(ctx, r.EventID, r.UserID)
@@ -69,38 +117,204 @@ func (r *Registration) Cancel(ctx context.Context) error {
 		return err
 	}
 	if !alreadyRegistered {
-		return errors.New("you are not registered for this event")
+		return ErrNotRegistered
 	}
 
-	query := `DELETE FROM registrations WHERE event_id = ? AND user_id = ?`
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM registrations WHERE event_id = ? AND user_id = ? RETURNING id, registered_at`
 
-	_, err = db.DB.ExecContext(ctx, query, r.EventID, r.UserID)
+	err = tx.QueryRowContext(ctx, query, r.EventID, r.UserID).Scan(&r.ID, &r.RegisteredAt)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotRegistered
+		}
 		if ctx.Err() == context.DeadlineExceeded {
-			return errors.New("request timeout while canceling registration")
+			return timeoutError("canceling registration")
 		}
 		if ctx.Err() == context.Canceled {
-			return errors.New("request was canceled while canceling registration")
+			return canceledError("canceling registration")
 		}
 		return err
 	}
 
+	if err := enqueueOutbox(ctx, tx, OutboxRegistrationCancelled, r); err != nil {
+		return err
+	}
+	if err := touchCalendarFeed(ctx, tx, r.UserID); err != nil {
+		return err
+	}
+	count, err := countRegistrations(ctx, tx, r.EventID)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	publishRegistrationCount(r.EventID, count)
 	return nil
 }
 
+func CountRegistrations(ctx context.Context, eventID int) (int, error) {
+	count, err := countRegistrations(ctx, db.ReadDB, eventID)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return 0, timeoutError("counting registrations")
+		}
+		return 0, err
+	}
+
+	return count, nil
+}
+
 func IsUserRegistered(ctx context.Context, eventID, userID int) (bool, error) {
 	query := `SELECT COUNT(*) FROM registrations WHERE event_id = ? AND user_id = ?`
 
-	row := db.DB.QueryRowContext(ctx, query, eventID, userID)
+	row := db.ReadDB.QueryRowContext(ctx, query, eventID, userID)
 
 	var count int
 	err := row.Scan(&count)
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
-			return false, errors.New("request timeout while checking registration")
+			return false, timeoutError("checking registration")
 		}
 		return false, err
 	}
 
 	return count > 0, nil
 }
+
+// returns one page of an event's attendees, oldest registration first
+func GetEventAttendees(ctx context.Context, eventID, page, limit int) ([]Attendee, int, error) {
+	var total int
+	countQuery := `
+	SELECT COUNT(*)
+	FROM registrations r
+	INNER JOIN users u ON u.id = r.user_id
+	WHERE r.event_id = ? AND u.deleted_at IS NULL
+	`
+
+	err := db.ReadDB.QueryRowContext(ctx, countQuery, eventID).Scan(&total)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, 0, timeoutError("counting attendees")
+		}
+		return nil, 0, err
+	}
+
+	attendees := make([]Attendee, 0)
+	offset := (page - 1) * limit
+	err = queryAttendees(ctx, eventID, limit, offset, func(attendee Attendee) error {
+		attendees = append(attendees, attendee)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return attendees, total, nil
+}
+
+// calls fn for every attendee of the event without loading them all
+// into memory, used for streaming exports
+func ForEachEventAttendee(ctx context.Context, eventID int, fn func(Attendee) error) error {
+	return queryAttendees(ctx, eventID, -1, 0, fn)
+}
+
+// a negative limit means no limit in SQLite
+func queryAttendees(ctx context.Context, eventID, limit, offset int, fn func(Attendee) error) error {
+	query := `
+	SELECT r.id, u.id, u.email, r.registered_at
+	FROM registrations r
+	INNER JOIN users u ON u.id = r.user_id
+	WHERE r.event_id = ? AND u.deleted_at IS NULL
+	ORDER BY r.registered_at, r.id
+	LIMIT ? OFFSET ?
+	`
+
+	rows, err := db.ReadDB.QueryContext(ctx, query, eventID, limit, offset)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return timeoutError("fetching attendees")
+		}
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var attendee Attendee
+		err := rows.Scan(&attendee.RegistrationID, &attendee.UserID, &attendee.Email, &attendee.RegisteredAt)
+		if err != nil {
+			return err
+		}
+		if err := fn(attendee); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// returns a page of the user's registrations with their event details
+func GetUserRegistrations(ctx context.Context, userID int, filter string, page, limit int) ([]UserRegistration, int, error) {
+	condition, order := timeFilterClause(filter, "e.dateTime")
+	args := []any{userID}
+	if condition != "" {
+		args = append(args, time.Now().UTC())
+	}
+
+	var total int
+	countQuery := `
+	SELECT COUNT(*)
+	FROM registrations r
+	INNER JOIN events e ON e.id = r.event_id
+	WHERE r.user_id = ? AND e.deleted_at IS NULL` + condition
+
+	err := db.ReadDB.QueryRowContext(ctx, countQuery, args...).Scan(&total)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, 0, timeoutError("counting registrations")
+		}
+		return nil, 0, err
+	}
+
+	query := `
+	SELECT r.id, r.registered_at, ` + eventColumnsOf("e") + `
+	FROM registrations r
+	INNER JOIN events e ON e.id = r.event_id
+	WHERE r.user_id = ? AND e.deleted_at IS NULL` + condition + `
+	ORDER BY ` + order + `
+	LIMIT ? OFFSET ?
+	`
+	rows, err := db.ReadDB.QueryContext(ctx, query, append(args, limit, (page-1)*limit)...)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, 0, timeoutError("fetching registrations")
+		}
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	registrations := make([]UserRegistration, 0)
+	for rows.Next() {
+		registration := UserRegistration{Status: RegistrationStatusRegistered}
+		dest := append([]any{&registration.RegistrationID, &registration.RegisteredAt}, registration.Event.scanFields()...)
+		err := rows.Scan(dest...)
+		if err != nil {
+			return nil, 0, err
+		}
+		registrations = append(registrations, registration)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return registrations, total, nil
+}
@@ -0,0 +1,161 @@
+package models
+
+import (
+	"REST-API/live"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func saveTestEvent(t *testing.T) Event {
+	t.Helper()
+
+	event := Event{
+		Name:        "Registration Event",
+		Description: "An event people register for",
+		Location:    "Somewhere",
+		DateTime:    time.Now().Add(24 * time.Hour),
+		UserID:      1,
+		Status:      EventStatusPublished,
+	}
+	if err := event.Save(context.Background()); err != nil {
+		t.Fatalf("could not save test event: %v", err)
+	}
+	return event
+}
+
+func TestGetEventAttendees(t *testing.T) {
+	setupTestDB(t)
+	event := saveTestEvent(t)
+
+	for _, email := range []string{"first@example.com", "second@example.com", "third@example.com"} {
+		user := User{Email: email, Password: "secret123"}
+		user.Save(context.Background())
+		registration := Registration{EventID: event.ID, UserID: user.ID}
+		if err := registration.Save(context.Background()); err != nil {
+			t.Fatalf("expected no error registering, got: %v", err)
+		}
+		if registration.RegisteredAt.IsZero() {
+			t.Error("expected registration time to be set after save")
+		}
+	}
+
+	attendees, total, err := GetEventAttendees(context.Background(), event.ID, 1, 2)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("expected total 3, got %d", total)
+	}
+	if len(attendees) != 2 || attendees[0].Email != "first@example.com" {
+		t.Errorf("expected first page of 2 attendees in registration order, got %v", attendees)
+	}
+
+	var exported int
+	ForEachEventAttendee(context.Background(), event.ID, func(Attendee) error {
+		exported++
+		return nil
+	})
+	if exported != 3 {
+		t.Errorf("expected all 3 attendees to be exported, got %d", exported)
+	}
+}
+
+func TestCoHosts(t *testing.T) {
+	setupTestDB(t)
+	event := saveTestEvent(t)
+
+	user := User{Email: "cohost@example.com", Password: "secret123"}
+	user.Save(context.Background())
+
+	if err := AddCoHost(context.Background(), event.ID, user.ID); err != nil {
+		t.Fatalf("expected no error adding co-host, got: %v", err)
+	}
+	if err := AddCoHost(context.Background(), event.ID, user.ID); err == nil {
+		t.Error("expected error adding the same co-host twice")
+	}
+
+	isCoHost, _ := IsEventCoHost(context.Background(), event.ID, user.ID)
+	if !isCoHost {
+		t.Error("expected user to be a co-host")
+	}
+
+	RemoveCoHost(context.Background(), event.ID, user.ID)
+	isCoHost, _ = IsEventCoHost(context.Background(), event.ID, user.ID)
+	if isCoHost {
+		t.Error("expected user to no longer be a co-host")
+	}
+}
+
+func TestGetUserRegistrations(t *testing.T) {
+	setupTestDB(t)
+	upcoming := saveTestEvent(t)
+
+	past := saveTestEvent(t)
+	past.DateTime = time.Now().Add(-24 * time.Hour)
+	past.Update(context.Background())
+
+	for _, eventID := range []int{upcoming.ID, past.ID} {
+		registration := Registration{EventID: eventID, UserID: 1}
+		registration.Save(context.Background())
+	}
+
+	all, total, err := GetUserRegistrations(context.Background(), 1, TimeFilterAll, 1, 10)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if total != 2 || len(all) != 2 {
+		t.Errorf("expected 2 registrations, got total %d and %d results", total, len(all))
+	}
+
+	upcomingOnly, total, _ := GetUserRegistrations(context.Background(), 1, TimeFilterUpcoming, 1, 10)
+	if total != 1 || upcomingOnly[0].Event.ID != upcoming.ID {
+		t.Errorf("expected only the upcoming event, got %v", upcomingOnly)
+	}
+	if upcomingOnly[0].Event.Name != upcoming.Name {
+		t.Errorf("expected event details to be joined, got %v", upcomingOnly[0].Event)
+	}
+
+	pastOnly, total, _ := GetUserRegistrations(context.Background(), 1, TimeFilterPast, 1, 10)
+	if total != 1 || pastOnly[0].Event.ID != past.ID {
+		t.Errorf("expected only the past event, got %v", pastOnly)
+	}
+}
+
+func TestRegistration_PublishesCount(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+
+	event := saveTestEvent(t)
+	subscription, err := live.Subscribe(func(m live.Message) bool { return m.EventID == event.ID }, "")
+	if err != nil {
+		t.Fatalf("could not subscribe: %v", err)
+	}
+	defer subscription.Close()
+
+	registration := Registration{EventID: event.ID, UserID: 1}
+	if err := registration.Save(ctx); err != nil {
+		t.Fatalf("could not register: %v", err)
+	}
+	if err := registration.Cancel(ctx); err != nil {
+		t.Fatalf("could not cancel registration: %v", err)
+	}
+
+	for _, want := range []int{1, 0} {
+		var message live.Message
+		select {
+		case message = <-subscription.Messages:
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for a count of %d", want)
+		}
+
+		var count RegistrationCount
+		if err := json.Unmarshal(message.Data, &count); err != nil {
+			t.Fatalf("could not decode %s: %v", message.Data, err)
+		}
+		if message.Type != LiveRegistrations || count.Registrations != want {
+			t.Errorf("expected a count of %d, got %s %s", want, message.Type, message.Data)
+		}
+	}
+}
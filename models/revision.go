@@ -0,0 +1,145 @@
+package models
+
+import (
+	"REST-API/db"
+	"context"
+	"database/sql"
+	"time"
+)
+
+// the editable details of an event, as captured by a revision
+type EventDetails struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Location    string     `json:"location"`
+	DateTime    time.Time  `json:"dateTime"`
+	EndDateTime *time.Time `json:"endDateTime,omitempty"`
+}
+
+// a previous version of an event, revision 1 being the original
+type EventRevision struct {
+	EventID  int `json:"eventId"`
+	Revision int `json:"revision"`
+	EventDetails
+	CreatedAt time.Time `json:"createdAt"` // when this version was replaced
+}
+
+func (e Event) Details() EventDetails {
+	return EventDetails{
+		Name:        e.Name,
+		Description: e.Description,
+		Location:    e.Location,
+		DateTime:    e.DateTime,
+		EndDateTime: e.EndDateTime,
+	}
+}
+
+func (e *Event) SetDetails(details EventDetails) {
+	e.Name = details.Name
+	e.Description = details.Description
+	e.Location = details.Location
+	e.DateTime = details.DateTime
+	e.EndDateTime = details.EndDateTime
+}
+
+// copies the event's current details into the next revision
+func saveEventRevision(ctx context.Context, exec db.Executor, eventID int) error {
+	query := `
+	INSERT INTO event_revisions(event_id, revision, name, description, location, dateTime, end_date_time, created_at)
+	SELECT id, (SELECT COALESCE(MAX(revision), 0) + 1 FROM event_revisions WHERE event_id = ?),
+		name, description, location, dateTime, end_date_time, ?
+	FROM events
+	WHERE id = ? AND deleted_at IS NULL
+	`
+
+	_, err := exec.ExecContext(ctx, query, eventID, time.Now().UTC(), eventID)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return timeoutError("saving event revision")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// returns a page of the event's revisions, newest first
+func GetEventRevisions(ctx context.Context, eventID, page, limit int) ([]EventRevision, int, error) {
+	var total int
+	countQuery := `SELECT COUNT(*) FROM event_revisions WHERE event_id = ?`
+
+	err := db.ReadDB.QueryRowContext(ctx, countQuery, eventID).Scan(&total)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, 0, timeoutError("counting event revisions")
+		}
+		return nil, 0, err
+	}
+
+	query := `
+	SELECT event_id, revision, name, description, location, dateTime, end_date_time, created_at
+	FROM event_revisions
+	WHERE event_id = ?
+	ORDER BY revision DESC
+	LIMIT ? OFFSET ?
+	`
+	rows, err := db.ReadDB.QueryContext(ctx, query, eventID, limit, (page-1)*limit)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, 0, timeoutError("fetching event revisions")
+		}
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	revisions := make([]EventRevision, 0)
+	for rows.Next() {
+		var revision EventRevision
+		if err := rows.Scan(revision.scanFields()...); err != nil {
+			return nil, 0, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return revisions, total, nil
+}
+
+// returns a single revision, nil if it doesn't exist
+func GetEventRevision(ctx context.Context, eventID, revisionNumber int) (*EventRevision, error) {
+	query := `
+	SELECT event_id, revision, name, description, location, dateTime, end_date_time, created_at
+	FROM event_revisions
+	WHERE event_id = ? AND revision = ?
+	`
+
+	var revision EventRevision
+	err := db.ReadDB.QueryRowContext(ctx, query, eventID, revisionNumber).Scan(revision.scanFields()...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, timeoutError("fetching event revision")
+		}
+		return nil, err
+	}
+
+	return &revision, nil
+}
+
+func (r *EventRevision) scanFields() []any {
+	return []any{
+		&r.EventID,
+		&r.Revision,
+		&r.Name,
+		&r.Description,
+		&r.Location,
+		&r.DateTime,
+		&r.EndDateTime,
+		&r.CreatedAt,
+	}
+}
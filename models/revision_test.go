@@ -0,0 +1,48 @@
+package models
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUpdateSavesRevision(t *testing.T) {
+	setupTestDB(t)
+
+	event := saveTestEvent(t)
+	original := event.Name
+
+	for _, name := range []string{"Second Name", "Third Name"} {
+		event.Name = name
+		if err := event.Update(context.Background()); err != nil {
+			t.Fatalf("expected no error updating event, got: %v", err)
+		}
+	}
+
+	revisions, total, err := GetEventRevisions(context.Background(), event.ID, 1, 10)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if total != 2 || len(revisions) != 2 {
+		t.Fatalf("expected 2 revisions, got %d", total)
+	}
+	// newest first, each holding the version that was replaced
+	if revisions[0].Revision != 2 || revisions[0].Name != "Second Name" {
+		t.Errorf("expected revision 2 to be %q, got %+v", "Second Name", revisions[0])
+	}
+	if revisions[1].Revision != 1 || revisions[1].Name != original {
+		t.Errorf("expected revision 1 to be the original %q, got %+v", original, revisions[1])
+	}
+
+	revision, err := GetEventRevision(context.Background(), event.ID, 1)
+	if err != nil || revision == nil {
+		t.Fatalf("expected to find revision 1, got %v (%v)", revision, err)
+	}
+	if !revision.DateTime.Equal(event.DateTime) {
+		t.Errorf("expected revision dateTime %v, got %v", event.DateTime, revision.DateTime)
+	}
+
+	missing, err := GetEventRevision(context.Background(), event.ID, 3)
+	if err != nil || missing != nil {
+		t.Errorf("expected no revision 3, got %v (%v)", missing, err)
+	}
+}
@@ -6,7 +6,7 @@ import (
 	"REST-API/utils"
 	"context"
 	"database/sql"
-	"errors"
+	"strings"
 	"time"
 )
 
@@ -17,13 +17,20 @@ type User struct {
 	Role     string `json:"role"`
 }
 
+// creates the user with the user role. u.Role is ignored, since signup binds
+// it from the request body.
 func (u *User) Save(ctx context.Context) error {
+	return u.SaveWithRole(ctx, "user")
+}
+
+// creates the user with role, in a single write
+func (u *User) SaveWithRole(ctx context.Context, role string) error {
 	existingUser, err := GetUserByEmail(ctx, u.Email)
 	if err != nil {
 		return err
 	}
 	if existingUser != nil {
-		return errors.New("email already registered")
+		return ErrEmailTaken
 	}
 
 	hashedPassword, err := utils.HashPassword(u.Password)
@@ -33,13 +40,17 @@ func (u *User) Save(ctx context.Context) error {
 
 	query := `INSERT INTO users(email, password, role) VALUES (?, ?, ?)`
 
-	result, err := db.DB.ExecContext(ctx, query, u.Email, string(hashedPassword), "user")
+	result, err := db.DB.ExecContext(ctx, query, u.Email, string(hashedPassword), role)
 	if err != nil {
+		// a soft-deleted account still holds on to its email
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return ErrEmailTaken
+		}
 		if ctx.Err() == context.DeadlineExceeded {
-			return errors.New("request timeout while creating user")
+			return timeoutError("creating user")
 		}
 		if ctx.Err() == context.Canceled {
-			return errors.New("request was canceled while creating user")
+			return canceledError("creating user")
 		}
 		return err
 	}
@@ -50,14 +61,14 @@ func (u *User) Save(ctx context.Context) error {
 	}
 
 	u.ID = int(id)
-	u.Role = "user"
+	u.Role = role
 	return nil
 }
 
 func GetUserByEmail(ctx context.Context, email string) (*User, error) {
-	query := `SELECT id, email, password, role FROM users WHERE email = ?`
+	query := `SELECT id, email, password, role FROM users WHERE email = ? AND deleted_at IS NULL`
 
-	row := db.DB.QueryRowContext(ctx, query, email)
+	row := db.ReadDB.QueryRowContext(ctx, query, email)
 
 	var user User
 	err := row.Scan(&user.ID, &user.Email, &user.Password, &user.Role)
@@ -66,7 +77,27 @@ func GetUserByEmail(ctx context.Context, email string) (*User, error) {
 			return nil, nil
 		}
 		if ctx.Err() == context.DeadlineExceeded {
-			return nil, errors.New("request timeout while fetching user")
+			return nil, timeoutError("fetching user")
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func GetUserByID(ctx context.Context, id int) (*User, error) {
+	query := `SELECT id, email, password, role FROM users WHERE id = ? AND deleted_at IS NULL`
+
+	row := db.ReadDB.QueryRowContext(ctx, query, id)
+
+	var user User
+	err := row.Scan(&user.ID, &user.Email, &user.Password, &user.Role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, timeoutError("fetching user")
 		}
 		return nil, err
 	}
@@ -80,12 +111,12 @@ func (u *User) ValidateCredentials(ctx context.Context) error {
 		return err
 	}
 	if existingUser == nil {
-		return errors.New("invalid credentials")
+		return ErrInvalidCredentials
 	}
 
 	passwordMatch := utils.CheckPasswordHash(u.Password, existingUser.Password)
 	if !passwordMatch {
-		return errors.New("invalid credentials")
+		return ErrInvalidCredentials
 	}
 
 	u.ID = existingUser.ID
@@ -96,14 +127,15 @@ func (u *User) ValidateCredentials(ctx context.Context) error {
 
 // stores a refresh token in the database
 func (u *User) SaveRefreshToken(ctx context.Context, token string) error {
-	expiresAt := time.Now().Add(config.App.RefreshTokenExpiry)
+	// stored in UTC so PurgeExpiredRefreshTokens can compare it in SQL
+	expiresAt := time.Now().Add(config.Get().RefreshTokenExpiry).UTC()
 
 	query := `INSERT INTO refresh_tokens(token, user_id, expires_at) VALUES (?, ?, ?)`
 
 	_, err := db.DB.ExecContext(ctx, query, token, u.ID, expiresAt)
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
-			return errors.New("request timeout while saving refresh token")
+			return timeoutError("saving refresh token")
 		}
 		return err
 	}
@@ -117,26 +149,26 @@ func ValidateRefreshToken(ctx context.Context, token string) (*User, error) {
 		SELECT u.id, u.email, u.password, u.role, rt.expires_at
 		FROM users u
 		INNER JOIN refresh_tokens rt ON u.id = rt.user_id
-		WHERE rt.token = ?
+		WHERE rt.token = ? AND u.deleted_at IS NULL
 	`
 
 	var user User
 	var expiresAt time.Time
 
-	err := db.DB.QueryRowContext(ctx, query, token).Scan(&user.ID, &user.Email, &user.Password, &user.Role, &expiresAt)
+	err := db.ReadDB.QueryRowContext(ctx, query, token).Scan(&user.ID, &user.Email, &user.Password, &user.Role, &expiresAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("invalid refresh token")
+			return nil, ErrInvalidRefreshToken
 		}
 		if ctx.Err() == context.DeadlineExceeded {
-			return nil, errors.New("request timeout while validating refresh token")
+			return nil, timeoutError("validating refresh token")
 		}
 		return nil, err
 	}
 
 	// Check if token is expired
 	if time.Now().After(expiresAt) {
-		return nil, errors.New("refresh token expired")
+		return nil, ErrRefreshTokenExpired
 	}
 
 	return &user, nil
@@ -149,7 +181,7 @@ func DeleteRefreshToken(ctx context.Context, token string) error {
 	_, err := db.DB.ExecContext(ctx, query, token)
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
-			return errors.New("request timeout while deleting refresh token")
+			return timeoutError("deleting refresh token")
 		}
 		return err
 	}
@@ -164,7 +196,7 @@ func (u *User) DeleteAllRefreshTokens(ctx context.Context) error {
 	_, err := db.DB.ExecContext(ctx, query, u.ID)
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
-			return errors.New("request timeout while deleting refresh tokens")
+			return timeoutError("deleting refresh tokens")
 		}
 		return err
 	}
@@ -193,3 +225,180 @@ func (u *User) RotateRefreshToken(ctx context.Context, oldToken string) (string,
 
 	return newToken, nil
 }
+
+// replaces the user's password and signs them out everywhere
+func SetUserPassword(ctx context.Context, id int, password string) error {
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE users SET password = ? WHERE id = ? AND deleted_at IS NULL`
+
+	result, err := tx.ExecContext(ctx, query, hashedPassword, id)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return timeoutError("updating password")
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE user_id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// removes refresh tokens past their expiry, which can no longer be used
+func PurgeExpiredRefreshTokens(ctx context.Context) (int64, error) {
+	query := `DELETE FROM refresh_tokens WHERE expires_at < ?`
+
+	result, err := db.DB.ExecContext(ctx, query, time.Now().UTC())
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return 0, timeoutError("purging refresh tokens")
+		}
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// valid values for User.Role
+var UserRoles = []string{"user", "admin"}
+
+// changes the user's role and returns the previous one. Existing access
+// tokens keep the old role until they expire, refresh tokens pick up the new one.
+func SetUserRole(ctx context.Context, id int, role string) (string, error) {
+	user, err := GetUserByID(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if user == nil {
+		return "", ErrUserNotFound
+	}
+
+	query := `UPDATE users SET role = ? WHERE id = ? AND deleted_at IS NULL`
+
+	_, err = db.DB.ExecContext(ctx, query, role, id)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", timeoutError("updating user role")
+		}
+		return "", err
+	}
+
+	return user.Role, nil
+}
+
+// soft-deletes the user and signs them out everywhere, their account can be
+// restored by an admin until PurgeDeletedUsers removes it for good
+func DeleteUser(ctx context.Context, id int) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE users SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`
+
+	result, err := tx.ExecContext(ctx, query, time.Now().UTC(), id)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return timeoutError("deleting user")
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE user_id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// undoes a soft delete
+func RestoreUser(ctx context.Context, id int) error {
+	query := `UPDATE users SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL`
+
+	result, err := db.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return timeoutError("restoring user")
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrDeletedUserNotFound
+	}
+
+	return nil
+}
+
+// tables holding rows that reference users(id), cleared before a
+// user is hard-deleted so nothing is left dangling
+var userDependentTables = []string{"registrations", "refresh_tokens", "calendar_feeds", "event_cohosts", "notifications", "idempotency_keys"}
+
+// hard-deletes users soft-deleted before cutoff along with their dependent rows.
+// Users who still own events are kept until those events are purged.
+func PurgeDeletedUsers(ctx context.Context, cutoff time.Time) (int64, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	expired := `
+	SELECT id FROM users
+	WHERE deleted_at IS NOT NULL AND deleted_at < ?
+	AND NOT EXISTS (SELECT 1 FROM events WHERE events.user_id = users.id)
+	`
+	for _, table := range userDependentTables {
+		query := `DELETE FROM ` + table + ` WHERE user_id IN (` + expired + `)`
+		if _, err := tx.ExecContext(ctx, query, cutoff.UTC()); err != nil {
+			return 0, err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id IN (`+expired+`)`, cutoff.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, tx.Commit()
+}
@@ -1,8 +1,11 @@
 package models
 
 import (
+	"REST-API/db"
 	"context"
+	"errors"
 	"testing"
+	"time"
 )
 
 func TestSaveUser(t *testing.T) {
@@ -22,6 +25,26 @@ func TestSaveUser(t *testing.T) {
 	}
 }
 
+func TestSaveUser_Role(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+
+	// signup binds Role from the request body, Save must not trust it
+	user := User{Email: "sneaky@example.com", Password: "secret123", Role: "admin"}
+	user.Save(ctx)
+	admin := User{Email: "ops@example.com", Password: "secret123"}
+	if err := admin.SaveWithRole(ctx, "admin"); err != nil {
+		t.Fatalf("expected no error saving admin, got: %v", err)
+	}
+
+	for _, want := range []User{{ID: user.ID, Role: "user"}, {ID: admin.ID, Role: "admin"}} {
+		saved, _ := GetUserByID(ctx, want.ID)
+		if saved == nil || saved.Role != want.Role {
+			t.Errorf("expected user %d to be saved as %s, got %+v", want.ID, want.Role, saved)
+		}
+	}
+}
+
 func TestSaveUser_DuplicateEmail(t *testing.T) {
 	setupTestDB(t)
 
@@ -33,8 +56,8 @@ func TestSaveUser_DuplicateEmail(t *testing.T) {
 	if err == nil {
 		t.Error("expected error for duplicate email, got nil")
 	}
-	if err.Error() != "email already registered" {
-		t.Errorf("expected 'email already registered' error, got: %v", err)
+	if !errors.Is(err, ErrConflict) || err.Error() != "email already registered" {
+		t.Errorf("expected 'email already registered' conflict, got: %v", err)
 	}
 }
 
@@ -95,3 +118,74 @@ func TestValidateCredentials(t *testing.T) {
 		t.Error("expected error for non-existent user, got nil")
 	}
 }
+
+func TestDeleteRestoreAndPurgeUser(t *testing.T) {
+	setupTestDB(t)
+
+	user := User{Email: "leaving@example.com", Password: "secret123"}
+	user.Save(context.Background())
+
+	if err := DeleteUser(context.Background(), user.ID); err != nil {
+		t.Fatalf("expected no error on delete, got: %v", err)
+	}
+	found, _ := GetUserByEmail(context.Background(), "leaving@example.com")
+	if found != nil {
+		t.Error("expected deleted user to be hidden")
+	}
+
+	if err := RestoreUser(context.Background(), user.ID); err != nil {
+		t.Fatalf("expected no error on restore, got: %v", err)
+	}
+	found, _ = GetUserByEmail(context.Background(), "leaving@example.com")
+	if found == nil {
+		t.Fatal("expected restored user to be visible again")
+	}
+
+	DeleteUser(context.Background(), user.ID)
+	purged, err := PurgeDeletedUsers(context.Background(), time.Now().Add(time.Minute))
+	if err != nil || purged != 1 {
+		t.Fatalf("expected 1 user purged, got %d (%v)", purged, err)
+	}
+	if err := RestoreUser(context.Background(), user.ID); err == nil {
+		t.Error("expected purged user to be gone for good")
+	}
+}
+
+func TestSetUserPassword(t *testing.T) {
+	setupTestDB(t)
+
+	user := User{Email: "forgetful@example.com", Password: "secret123"}
+	user.Save(context.Background())
+	user.SaveRefreshToken(context.Background(), "still-signed-in")
+
+	if err := SetUserPassword(context.Background(), user.ID, "newsecret456"); err != nil {
+		t.Fatalf("expected no error setting password, got: %v", err)
+	}
+
+	login := User{Email: "forgetful@example.com", Password: "newsecret456"}
+	if err := login.ValidateCredentials(context.Background()); err != nil {
+		t.Errorf("expected the new password to work, got: %v", err)
+	}
+	if _, err := ValidateRefreshToken(context.Background(), "still-signed-in"); err == nil {
+		t.Error("expected existing sessions to be signed out")
+	}
+	if err := SetUserPassword(context.Background(), 999, "newsecret456"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got: %v", err)
+	}
+}
+
+func TestPurgeExpiredRefreshTokens(t *testing.T) {
+	setupTestDB(t)
+
+	user := User{ID: 1}
+	user.SaveRefreshToken(context.Background(), "current")
+	db.DB.Exec(`INSERT INTO refresh_tokens(token, user_id, expires_at) VALUES ('expired', 1, ?)`, time.Now().Add(-time.Hour).UTC())
+
+	purged, err := PurgeExpiredRefreshTokens(context.Background())
+	if err != nil || purged != 1 {
+		t.Fatalf("expected 1 token purged, got %d (%v)", purged, err)
+	}
+	if _, err := ValidateRefreshToken(context.Background(), "current"); err != nil {
+		t.Errorf("expected the current token to be kept, got: %v", err)
+	}
+}
@@ -0,0 +1,366 @@
+package models
+
+import (
+	"REST-API/db"
+	"REST-API/utils"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// the outbox topics webhooks can subscribe to
+var WebhookTopics = []string{
+	OutboxEventCreated,
+	OutboxEventUpdated,
+	OutboxEventCancelled,
+	OutboxEventDeleted,
+	OutboxRegistrationCreated,
+	OutboxRegistrationCancelled,
+}
+
+const (
+	// sent only by the test endpoint, so receivers can tell it apart
+	WebhookTopicTest = "webhook.test"
+
+	// outbox topic for one delivery to one endpoint
+	OutboxWebhookDelivery = "webhook.deliver"
+)
+
+// an endpoint notified of changes to events its owner organizes, or to every
+// event when its owner is an admin
+type Webhook struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"userId"`
+	URL         string    `json:"url" validate:"required,http_url,max=2000"`
+	Secret      string    `json:"-"` // signs deliveries, only shown once when created
+	Topics      []string  `json:"topics" validate:"required,min=1,dive,oneof=event.created event.updated event.cancelled event.deleted registration.created registration.cancelled"`
+	Description string    `json:"description" validate:"max=200"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// the body POSTed to a webhook. ID is the same for every endpoint and every
+// retry of one change, so receivers can drop duplicates.
+type WebhookMessage struct {
+	ID        string          `json:"id"`
+	Topic     string          `json:"topic"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// the payload of a webhook.deliver outbox message
+type WebhookDeliveryJob struct {
+	WebhookID int            `json:"webhookId"`
+	Message   WebhookMessage `json:"message"`
+}
+
+// one attempt to deliver a message to a webhook
+type WebhookDelivery struct {
+	ID          int             `json:"id"`
+	WebhookID   int             `json:"webhookId"`
+	MessageID   string          `json:"messageId"`
+	Topic       string          `json:"topic"`
+	Attempt     int             `json:"attempt"`
+	RequestBody json.RawMessage `json:"requestBody"`
+	StatusCode  int             `json:"statusCode"` // 0 when no response was received
+	Error       string          `json:"error,omitempty"`
+	DurationMs  int64           `json:"durationMs"`
+	Succeeded   bool            `json:"succeeded"`
+	CreatedAt   time.Time       `json:"createdAt"`
+}
+
+const webhookColumns = `id, user_id, url, secret, topics, description, active, created_at`
+
+func scanWebhook(row interface{ Scan(...any) error }) (*Webhook, error) {
+	var webhook Webhook
+	var topics string
+	err := row.Scan(&webhook.ID, &webhook.UserID, &webhook.URL, &webhook.Secret, &topics, &webhook.Description, &webhook.Active, &webhook.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(topics), &webhook.Topics); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// creates the webhook with a newly generated signing secret
+func (w *Webhook) Save(ctx context.Context) error {
+	secret, err := utils.GenerateWebhookSecret()
+	if err != nil {
+		return err
+	}
+	topics, err := json.Marshal(w.Topics)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO webhooks(user_id, url, secret, topics, description, active, created_at)
+	VALUES (?, ?, ?, ?, ?, 1, ?)
+	`
+
+	createdAt := time.Now().UTC()
+	result, err := db.DB.ExecContext(ctx, query, w.UserID, w.URL, secret, string(topics), w.Description, createdAt)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return timeoutError("saving webhook")
+		}
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	w.ID = int(id)
+	w.Secret = secret
+	w.Active = true
+	w.CreatedAt = createdAt
+	return nil
+}
+
+func GetWebhookByID(ctx context.Context, id int) (*Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = ?`
+
+	webhook, err := scanWebhook(db.ReadDB.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, timeoutError("fetching webhook")
+		}
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+// returns the user's webhooks, oldest first
+func GetUserWebhooks(ctx context.Context, userID int) ([]Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE user_id = ? ORDER BY id`
+
+	rows, err := db.ReadDB.QueryContext(ctx, query, userID)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, timeoutError("fetching webhooks")
+		}
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := make([]Webhook, 0)
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+// saves the webhook's URL, topics, description and active flag
+func (w *Webhook) Update(ctx context.Context) error {
+	topics, err := json.Marshal(w.Topics)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE webhooks SET url = ?, topics = ?, description = ?, active = ? WHERE id = ?`
+
+	result, err := db.DB.ExecContext(ctx, query, w.URL, string(topics), w.Description, w.Active, w.ID)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return timeoutError("updating webhook")
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// deletes the webhook along with its delivery log. Deliveries already
+// queued for it are dropped when they come up.
+func DeleteWebhook(ctx context.Context, id int) error {
+	result, err := db.DB.ExecContext(ctx, `DELETE FROM webhooks WHERE id = ?`, id)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return timeoutError("deleting webhook")
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// queues one delivery of message for every active webhook subscribed to its
+// topic that may see the event, all in one transaction. Returns how many
+// were queued.
+func QueueWebhookDeliveries(ctx context.Context, message WebhookMessage, eventID int) (int, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// admins see every event, everyone else only the ones they own or co-host
+	query := `
+	SELECT w.id
+	FROM webhooks w
+	JOIN users u ON u.id = w.user_id
+	WHERE w.active = 1
+	AND u.deleted_at IS NULL
+	AND EXISTS (SELECT 1 FROM json_each(w.topics) WHERE value = ?)
+	AND (
+		u.role = 'admin'
+		OR w.user_id = (SELECT user_id FROM events WHERE id = ?)
+		OR EXISTS (SELECT 1 FROM event_cohosts WHERE event_id = ? AND user_id = w.user_id)
+	)
+	ORDER BY w.id
+	`
+
+	rows, err := tx.QueryContext(ctx, query, message.Topic, eventID, eventID)
+	if err != nil {
+		return 0, err
+	}
+	var webhookIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		webhookIDs = append(webhookIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, id := range webhookIDs {
+		job := WebhookDeliveryJob{WebhookID: id, Message: message}
+		if err := enqueueOutbox(ctx, tx, OutboxWebhookDelivery, job); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(webhookIDs), tx.Commit()
+}
+
+// appends an attempt to the webhook's delivery log
+func RecordWebhookDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	query := `
+	INSERT INTO webhook_deliveries(webhook_id, message_id, topic, attempt, request_body, status_code, error, duration_ms, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	delivery.CreatedAt = time.Now().UTC()
+	result, err := db.DB.ExecContext(ctx, query,
+		delivery.WebhookID, delivery.MessageID, delivery.Topic, delivery.Attempt, string(delivery.RequestBody),
+		delivery.StatusCode, delivery.Error, delivery.DurationMs, delivery.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	delivery.ID = int(id)
+	delivery.Succeeded = delivery.succeeded()
+	return nil
+}
+
+func (d WebhookDelivery) succeeded() bool {
+	return d.Error == "" && d.StatusCode >= 200 && d.StatusCode < 300
+}
+
+// returns a page of the webhook's delivery log, newest first
+func GetWebhookDeliveries(ctx context.Context, webhookID, page, limit int) ([]WebhookDelivery, int, error) {
+	var total int
+	countQuery := `SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_id = ?`
+
+	err := db.ReadDB.QueryRowContext(ctx, countQuery, webhookID).Scan(&total)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, 0, timeoutError("counting webhook deliveries")
+		}
+		return nil, 0, err
+	}
+
+	query := `
+	SELECT id, webhook_id, message_id, topic, attempt, request_body, status_code, error, duration_ms, created_at
+	FROM webhook_deliveries
+	WHERE webhook_id = ?
+	ORDER BY id DESC
+	LIMIT ? OFFSET ?
+	`
+	rows, err := db.ReadDB.QueryContext(ctx, query, webhookID, limit, (page-1)*limit)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, 0, timeoutError("fetching webhook deliveries")
+		}
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]WebhookDelivery, 0)
+	for rows.Next() {
+		var delivery WebhookDelivery
+		err := rows.Scan(
+			&delivery.ID,
+			&delivery.WebhookID,
+			&delivery.MessageID,
+			&delivery.Topic,
+			&delivery.Attempt,
+			(*[]byte)(&delivery.RequestBody),
+			&delivery.StatusCode,
+			&delivery.Error,
+			&delivery.DurationMs,
+			&delivery.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		delivery.Succeeded = delivery.succeeded()
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return deliveries, total, nil
+}
+
+// deletes delivery log entries older than cutoff
+func PurgeWebhookDeliveries(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := db.DB.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE created_at < ?`, cutoff.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
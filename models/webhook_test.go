@@ -0,0 +1,71 @@
+package models
+
+import (
+	"REST-API/db"
+	"context"
+	"testing"
+)
+
+func saveTestWebhook(t *testing.T, userID int, topics ...string) Webhook {
+	t.Helper()
+
+	webhook := Webhook{UserID: userID, URL: "https://example.com/hook", Topics: topics}
+	if err := webhook.Save(context.Background()); err != nil {
+		t.Fatalf("could not save test webhook: %v", err)
+	}
+	return webhook
+}
+
+func TestQueueWebhookDeliveries_OnlyToWebhooksThatMaySeeTheEvent(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+
+	_, err := db.DB.Exec(`INSERT INTO users(id, email, password, role) VALUES
+		(2, 'cohost@example.com', 'hashed', 'user'),
+		(3, 'stranger@example.com', 'hashed', 'user'),
+		(4, 'admin@example.com', 'hashed', 'admin')`)
+	if err != nil {
+		t.Fatalf("could not seed users: %v", err)
+	}
+	event := saveTestEvent(t)
+	AddCoHost(ctx, event.ID, 2)
+
+	owner := saveTestWebhook(t, 1, OutboxEventUpdated)
+	cohost := saveTestWebhook(t, 2, OutboxEventUpdated, OutboxEventCancelled)
+	saveTestWebhook(t, 3, OutboxEventUpdated)   // can't see the event
+	saveTestWebhook(t, 1, OutboxEventCancelled) // not subscribed
+	admin := saveTestWebhook(t, 4, OutboxEventUpdated)
+	admin.Active = false
+	admin.Update(ctx)
+
+	queued, err := QueueWebhookDeliveries(ctx, WebhookMessage{ID: "1", Topic: OutboxEventUpdated}, event.ID)
+	if err != nil {
+		t.Fatalf("expected no error queueing deliveries, got: %v", err)
+	}
+	if queued != 2 {
+		t.Fatalf("expected deliveries for the owner and co-host only, got %d", queued)
+	}
+
+	messages, _, _ := GetOutboxMessages(ctx, OutboxStatusPending, 1, 2)
+	if messages[0].Topic != OutboxWebhookDelivery || string(messages[0].Payload) == "" {
+		t.Errorf("expected webhook.deliver messages, got %+v", messages[0])
+	}
+
+	// switching the admin's webhook back on subscribes it to every event
+	admin.Active = true
+	admin.Update(ctx)
+	queued, _ = QueueWebhookDeliveries(ctx, WebhookMessage{ID: "2", Topic: OutboxEventUpdated}, event.ID)
+	if queued != 3 {
+		t.Errorf("expected the admin's webhook to be included, got %d deliveries", queued)
+	}
+
+	if _, err := GetWebhookByID(ctx, owner.ID); err != nil {
+		t.Fatalf("could not fetch webhook: %v", err)
+	}
+	if err := DeleteWebhook(ctx, cohost.ID); err != nil {
+		t.Fatalf("could not delete webhook: %v", err)
+	}
+	if _, err := GetWebhookByID(ctx, cohost.ID); err != ErrWebhookNotFound {
+		t.Errorf("expected ErrWebhookNotFound after delete, got %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package outbox
+
+import (
+	"REST-API/metrics"
+	"REST-API/models"
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// carries out one message. Returning an error retries it later, until it
+// runs out of attempts and is dead-lettered.
+type Handler func(ctx context.Context, message models.OutboxMessage) error
+
+type Options struct {
+	Workers      int           // messages delivered at once
+	PollInterval time.Duration // how often to look for due messages when idle
+	Lease        time.Duration // how long a handler gets before its message is claimed again
+	MaxAttempts  int
+	BackoffBase  time.Duration // wait before the first retry, doubling after each
+	BackoffMax   time.Duration
+}
+
+// delivers outbox messages to the handler registered for their topic with a
+// pool of workers. Messages are claimed from the database, so every message
+// committed is delivered at least once, even across crashes and restarts.
+type Dispatcher struct {
+	options  Options
+	handlers map[string]Handler
+	slots    chan struct{} // one per busy worker
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+func New(options Options) *Dispatcher {
+	return &Dispatcher{
+		options:  options,
+		handlers: map[string]Handler{},
+		slots:    make(chan struct{}, options.Workers),
+	}
+}
+
+// registers the handler for a topic, call before Start. Messages on topics
+// nothing handles are marked as done.
+func (d *Dispatcher) Handle(topic string, handler Handler) {
+	d.handlers[topic] = handler
+	for _, result := range []string{"delivered", "retried", "dead"} {
+		metrics.OutboxMessages.WithLabelValues(topic, result)
+	}
+}
+
+// starts polling for due messages
+func (d *Dispatcher) Start(ctx context.Context) {
+	ctx, d.cancel = context.WithCancel(ctx)
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.poll(ctx)
+	}()
+}
+
+// cancels in-flight deliveries and waits for the workers to return. Their
+// messages are put back to be delivered after the next start.
+func (d *Dispatcher) Stop() {
+	if d.cancel != nil {
+		d.cancel()
+	}
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) poll(ctx context.Context) {
+	for {
+		claimed, err := d.dispatchDue(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Could not claim outbox messages", "error", err)
+		}
+		// keep going straight away while there is a backlog
+		if claimed > 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(d.options.PollInterval):
+		}
+	}
+}
+
+// claims as many due messages as there are idle workers and hands them out
+func (d *Dispatcher) dispatchDue(ctx context.Context) (int, error) {
+	// wait for at least one idle worker, then take any others that are idle
+	select {
+	case d.slots <- struct{}{}:
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	idle := 1
+acquire:
+	for idle < cap(d.slots) {
+		select {
+		case d.slots <- struct{}{}:
+			idle++
+		default:
+			break acquire
+		}
+	}
+
+	messages, dead, err := models.ClaimOutboxMessages(ctx, idle, d.options.Lease, d.options.MaxAttempts)
+	for range idle - len(messages) {
+		<-d.slots
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// their last attempt never reported back, the worker crashed or hung
+	for _, message := range dead {
+		slog.Error("Outbox message ran out of attempts, dead-lettered", "outbox_id", message.ID, "topic", message.Topic, "attempt", message.Attempts, "error", message.LastError)
+		metrics.OutboxMessages.WithLabelValues(message.Topic, "dead").Inc()
+	}
+
+	for _, message := range messages {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			defer func() { <-d.slots }()
+			d.deliver(ctx, message)
+		}()
+	}
+	return len(messages), nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, message models.OutboxMessage) {
+	logger := slog.With("outbox_id", message.ID, "topic", message.Topic, "attempt", message.Attempts)
+	// recording the outcome must still happen while shutting down
+	recordCtx := context.WithoutCancel(ctx)
+
+	handler, ok := d.handlers[message.Topic]
+	if !ok {
+		logger.Debug("No handler for outbox topic, marking as done")
+		if err := models.CompleteOutboxMessage(recordCtx, message.ID); err != nil {
+			logger.Error("Could not mark outbox message as done", "error", err)
+		}
+		return
+	}
+
+	handlerCtx, cancel := context.WithTimeout(ctx, d.options.Lease)
+	err := safeHandle(handlerCtx, handler, message)
+	cancel()
+
+	switch {
+	case err == nil:
+		err = models.CompleteOutboxMessage(recordCtx, message.ID)
+		metrics.OutboxMessages.WithLabelValues(message.Topic, "delivered").Inc()
+
+	case ctx.Err() != nil:
+		// interrupted by shutdown rather than failing, so it's due again right
+		// away and the attempt doesn't count
+		err = models.ReleaseOutboxMessage(recordCtx, message.ID)
+
+	case message.Attempts >= d.options.MaxAttempts:
+		logger.Error("Outbox message failed for the last time, dead-lettered", "error", err)
+		err = models.FailOutboxMessage(recordCtx, message.ID, err.Error(), time.Time{})
+		metrics.OutboxMessages.WithLabelValues(message.Topic, "dead").Inc()
+
+	default:
+		retryIn := d.backoff(message.Attempts)
+		logger.Warn("Outbox message failed, will retry", "retry_in", retryIn.String(), "error", err)
+		err = models.FailOutboxMessage(recordCtx, message.ID, err.Error(), time.Now().Add(retryIn))
+		metrics.OutboxMessages.WithLabelValues(message.Topic, "retried").Inc()
+	}
+
+	// the lease runs out and the message is delivered again
+	if err != nil {
+		logger.Error("Could not record outbox delivery", "error", err)
+	}
+}
+
+// doubles the wait after each attempt up to BackoffMax, with up to 20%
+// jitter so messages that failed together don't all retry together
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	wait := d.options.BackoffBase
+	for i := 1; i < attempt && wait < d.options.BackoffMax; i++ {
+		wait *= 2
+	}
+	wait = min(wait, d.options.BackoffMax)
+	return wait - time.Duration(rand.Int64N(int64(wait)/5+1))
+}
+
+// a panicking handler fails its attempt rather than taking the server down
+func safeHandle(ctx context.Context, handler Handler, message models.OutboxMessage) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+	return handler(ctx, message)
+}
@@ -0,0 +1,120 @@
+package outbox
+
+import (
+	"REST-API/config"
+	"REST-API/db"
+	"REST-API/models"
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	t.Setenv("JWT_SECRET", "test-secret-key")
+	t.Setenv("DB_PATH", ":memory:")
+	if err := config.Load(nil); err != nil {
+		t.Fatalf("could not load config: %v", err)
+	}
+	db.InitDB()
+	t.Cleanup(func() { db.Close() })
+}
+
+func newTestDispatcher() *Dispatcher {
+	return New(Options{
+		Workers:      2,
+		PollInterval: 5 * time.Millisecond,
+		Lease:        time.Minute,
+		MaxAttempts:  3,
+		BackoffBase:  time.Millisecond,
+		BackoffMax:   2 * time.Millisecond,
+	})
+}
+
+// waits until no message is pending or processing
+func waitForOutbox(t *testing.T) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		_, pending, _ := models.GetOutboxMessages(context.Background(), models.OutboxStatusPending, 1, 1)
+		_, processing, _ := models.GetOutboxMessages(context.Background(), models.OutboxStatusProcessing, 1, 1)
+		if pending == 0 && processing == 0 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for the outbox to drain")
+}
+
+func TestDispatcher_RetriesThenDeadLetters(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+
+	var flakyCalls, brokenCalls atomic.Int32
+	dispatcher := newTestDispatcher()
+	dispatcher.Handle("flaky", func(ctx context.Context, message models.OutboxMessage) error {
+		// succeeds on its second attempt
+		if flakyCalls.Add(1) == 1 {
+			return errors.New("try again")
+		}
+		return nil
+	})
+	dispatcher.Handle("broken", func(ctx context.Context, message models.OutboxMessage) error {
+		brokenCalls.Add(1)
+		return errors.New("always fails")
+	})
+
+	models.EnqueueOutbox(ctx, "flaky", nil)
+	models.EnqueueOutbox(ctx, "broken", nil)
+	models.EnqueueOutbox(ctx, "unhandled", nil)
+
+	dispatcher.Start(ctx)
+	waitForOutbox(t)
+	dispatcher.Stop()
+
+	if flakyCalls.Load() != 2 || brokenCalls.Load() != 3 {
+		t.Errorf("expected 2 flaky and 3 broken attempts, got %d and %d", flakyCalls.Load(), brokenCalls.Load())
+	}
+
+	_, done, _ := models.GetOutboxMessages(ctx, models.OutboxStatusDone, 1, 10)
+	dead, _, _ := models.GetOutboxMessages(ctx, models.OutboxStatusDead, 1, 10)
+	if done != 2 || len(dead) != 1 || dead[0].Topic != "broken" || dead[0].LastError != "always fails" {
+		t.Errorf("expected flaky and unhandled done and broken dead-lettered, got %d done and %+v", done, dead)
+	}
+}
+
+func TestDispatcher_StopPutsInterruptedMessagesBack(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+
+	started := make(chan struct{})
+	dispatcher := newTestDispatcher()
+	dispatcher.Handle("slow", func(ctx context.Context, message models.OutboxMessage) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	models.EnqueueOutbox(ctx, "slow", nil)
+	dispatcher.Start(ctx)
+	<-started
+	dispatcher.Stop()
+
+	pending, _, _ := models.GetOutboxMessages(ctx, models.OutboxStatusPending, 1, 10)
+	if len(pending) != 1 || !pending[0].AvailableAt.Before(time.Now()) || pending[0].Attempts != 0 {
+		t.Errorf("expected the interrupted message to be due again with no attempt used, got %+v", pending)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	dispatcher := New(Options{BackoffBase: time.Second, BackoffMax: 10 * time.Second})
+
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 10: 10 * time.Second} {
+		got := dispatcher.backoff(attempt)
+		if got > want || got < want*4/5 {
+			t.Errorf("attempt %d: expected within 20%% under %v, got %v", attempt, want, got)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package routes
+
+import (
+	"REST-API/middleware"
+	"REST-API/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// loads the event from :id, allowing its owner, co-hosts and admins
+func loadManagedEvent(context *gin.Context) (*models.Event, bool) {
+	event, ok := loadEventParam(context)
+	if !ok {
+		return nil, false
+	}
+
+	isOrganizer, err := isEventOrganizer(context, event)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return nil, false
+	}
+	if !isOrganizer {
+		middleware.AbortWithProblem(context, http.StatusForbidden, "forbidden", "only the event's organizers can access this resource")
+		return nil, false
+	}
+
+	return event, true
+}
+
+// reports whether the current user is the event's owner, a co-host or an admin
+func isEventOrganizer(context *gin.Context, event *models.Event) (bool, error) {
+	userID := context.GetInt("userId")
+	if userID == 0 {
+		return false, nil
+	}
+	if event.UserID == userID || context.GetString("role") == "admin" {
+		return true, nil
+	}
+	return models.IsEventCoHost(context.Request.Context(), event.ID, userID)
+}
+
+// loads the event from :id, allowing only its owner and admins
+func loadOwnedEvent(context *gin.Context) (*models.Event, bool) {
+	event, ok := loadEventParam(context)
+	if !ok {
+		return nil, false
+	}
+
+	if event.UserID != context.GetInt("userId") && context.GetString("role") != "admin" {
+		middleware.AbortWithProblem(context, http.StatusForbidden, "forbidden", "only the event owner can perform this action")
+		return nil, false
+	}
+
+	return event, true
+}
+
+func loadEventParam(context *gin.Context) (*models.Event, bool) {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_event_id", "invalid event ID")
+		return nil, false
+	}
+
+	event, err := models.GetEventByID(context.Request.Context(), id)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return nil, false
+	}
+	if event == nil {
+		middleware.AbortWithError(context, models.ErrEventNotFound)
+		return nil, false
+	}
+
+	return event, true
+}
@@ -0,0 +1,136 @@
+package routes
+
+import (
+	"REST-API/middleware"
+	"REST-API/models"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getDeletedEvents handles GET /admin/events/deleted
+func getDeletedEvents(context *gin.Context) {
+	page, limit, ok := parsePagination(context)
+	if !ok {
+		return
+	}
+
+	events, total, err := models.GetDeletedEvents(context.Request.Context(), page, limit)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, paginatedResponse(events, total, page, limit))
+}
+
+// restoreEvent handles POST /admin/events/:id/restore
+func restoreEvent(context *gin.Context) {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_event_id", "invalid event ID")
+		return
+	}
+
+	err = models.RestoreEvent(context.Request.Context(), id)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	recordAudit(context, models.AuditEventRestore, "event", id, nil, nil)
+
+	context.JSON(http.StatusOK, gin.H{
+		"message": "Event restored",
+	})
+}
+
+// deleteUser handles DELETE /admin/users/:id
+func deleteUser(context *gin.Context) {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_user_id", "invalid user ID")
+		return
+	}
+
+	if id == context.GetInt("userId") {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "cannot_delete_self", "you can't delete your own account")
+		return
+	}
+
+	err = models.DeleteUser(context.Request.Context(), id)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	recordAudit(context, models.AuditUserDelete, "user", id, nil, nil)
+
+	context.JSON(http.StatusOK, gin.H{
+		"message": "user deleted",
+	})
+}
+
+// restoreUser handles POST /admin/users/:id/restore
+func restoreUser(context *gin.Context) {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_user_id", "invalid user ID")
+		return
+	}
+
+	err = models.RestoreUser(context.Request.Context(), id)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	recordAudit(context, models.AuditUserRestore, "user", id, nil, nil)
+
+	context.JSON(http.StatusOK, gin.H{
+		"message": "user restored",
+	})
+}
+
+// setUserRole handles PUT /admin/users/:id/role
+func setUserRole(context *gin.Context) {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_user_id", "invalid user ID")
+		return
+	}
+
+	// an admin demoting themselves could lock everyone out
+	if id == context.GetInt("userId") {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "cannot_change_own_role", "you can't change your own role")
+		return
+	}
+
+	var request struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := context.ShouldBindJSON(&request); err != nil || !slices.Contains(models.UserRoles, request.Role) {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_role", "role must be one of: "+strings.Join(models.UserRoles, ", "))
+		return
+	}
+
+	previous, err := models.SetUserRole(context.Request.Context(), id, request.Role)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	recordAudit(context, models.AuditUserRoleChange, "user", id,
+		gin.H{"role": previous}, gin.H{"role": request.Role})
+
+	context.JSON(http.StatusOK, gin.H{
+		"message": "user role updated",
+		"user": gin.H{
+			"id":   id,
+			"role": request.Role,
+		},
+	})
+}
@@ -0,0 +1,223 @@
+package routes
+
+import (
+	"REST-API/logging"
+	"REST-API/middleware"
+	"REST-API/models"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// flush the CSV export every this many rows so large rosters stream
+const exportFlushInterval = 100
+
+var attendeeExportHeader = []string{"Registration ID", "User ID", "Email", "Registered At"}
+
+// getEventRegistrations handles GET /events/:id/registrations
+func getEventRegistrations(context *gin.Context) {
+	event, ok := loadManagedEvent(context)
+	if !ok {
+		return
+	}
+
+	page, limit, ok := parsePagination(context)
+	if !ok {
+		return
+	}
+
+	attendees, total, err := models.GetEventAttendees(context.Request.Context(), event.ID, page, limit)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, paginatedResponse(attendees, total, page, limit))
+}
+
+// exportEventRegistrations handles GET /events/:id/registrations/export?format=csv|xlsx
+func exportEventRegistrations(context *gin.Context) {
+	format := context.DefaultQuery("format", "csv")
+	if format != "csv" && format != "xlsx" {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_format", "invalid format, must be csv or xlsx")
+		return
+	}
+
+	event, ok := loadManagedEvent(context)
+	if !ok {
+		return
+	}
+
+	filename := fmt.Sprintf("event-%d-attendees.%s", event.ID, format)
+	context.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	if format == "xlsx" {
+		exportAttendeesXLSX(context, event.ID)
+		return
+	}
+	exportAttendeesCSV(context, event.ID)
+}
+
+func exportAttendeesCSV(context *gin.Context, eventID int) {
+	context.Header("Content-Type", "text/csv; charset=utf-8")
+	context.Status(http.StatusOK)
+
+	writer := csv.NewWriter(context.Writer)
+	writer.Write(attendeeExportHeader)
+
+	written := 0
+	err := models.ForEachEventAttendee(context.Request.Context(), eventID, func(attendee models.Attendee) error {
+		err := writer.Write([]string{
+			strconv.Itoa(attendee.RegistrationID),
+			strconv.Itoa(attendee.UserID),
+			attendee.Email,
+			attendee.RegisteredAt.UTC().Format(time.RFC3339),
+		})
+		if err != nil {
+			return err
+		}
+
+		written++
+		if written%exportFlushInterval == 0 {
+			writer.Flush()
+			context.Writer.Flush()
+		}
+		return writer.Error()
+	})
+
+	writer.Flush()
+	if err != nil {
+		// headers are already sent, so the best we can do is cut the stream short
+		logging.FromContext(context.Request.Context()).Error("attendee export failed", "event_id", eventID, "error", err)
+		context.Abort()
+	}
+}
+
+func exportAttendeesXLSX(context *gin.Context, eventID int) {
+	file := excelize.NewFile()
+	defer file.Close()
+
+	sheet := file.GetSheetName(0)
+	stream, err := file.NewStreamWriter(sheet)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	dateStyle, err := file.NewStyle(&excelize.Style{NumFmt: 22}) // m/d/yy h:mm
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	header := make([]any, len(attendeeExportHeader))
+	for i, column := range attendeeExportHeader {
+		header[i] = column
+	}
+	stream.SetRow("A1", header)
+
+	row := 2
+	err = models.ForEachEventAttendee(context.Request.Context(), eventID, func(attendee models.Attendee) error {
+		cell, err := excelize.CoordinatesToCellName(1, row)
+		if err != nil {
+			return err
+		}
+		row++
+		return stream.SetRow(cell, []any{
+			attendee.RegistrationID,
+			attendee.UserID,
+			attendee.Email,
+			excelize.Cell{StyleID: dateStyle, Value: attendee.RegisteredAt.UTC()},
+		})
+	})
+	if err == nil {
+		err = stream.Flush()
+	}
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	context.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	context.Status(http.StatusOK)
+	if err := file.Write(context.Writer); err != nil {
+		logging.FromContext(context.Request.Context()).Error("attendee export failed", "event_id", eventID, "error", err)
+	}
+}
+
+// addCoHost handles POST /events/:id/cohosts
+func addCoHost(context *gin.Context) {
+	event, ok := loadOwnedEvent(context)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := context.ShouldBindJSON(&request); err != nil {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_email", "a valid email is required")
+		return
+	}
+
+	user, err := models.GetUserByEmail(context.Request.Context(), request.Email)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+	if user == nil {
+		middleware.AbortWithError(context, models.ErrUserNotFound)
+		return
+	}
+	if user.ID == event.UserID {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "owner_cannot_be_cohost", "the event owner can't be a co-host")
+		return
+	}
+
+	err = models.AddCoHost(context.Request.Context(), event.ID, user.ID)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	recordAudit(context, models.AuditCoHostAdd, "event", event.ID, nil, gin.H{"cohostUserId": user.ID})
+
+	context.JSON(http.StatusCreated, gin.H{
+		"message": "co-host added",
+		"cohost": gin.H{
+			"userId": user.ID,
+			"email":  user.Email,
+		},
+	})
+}
+
+// removeCoHost handles DELETE /events/:id/cohosts/:userId
+func removeCoHost(context *gin.Context) {
+	event, ok := loadOwnedEvent(context)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.Atoi(context.Param("userId"))
+	if err != nil {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_user_id", "invalid user ID")
+		return
+	}
+
+	err = models.RemoveCoHost(context.Request.Context(), event.ID, userID)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	recordAudit(context, models.AuditCoHostRemove, "event", event.ID, gin.H{"cohostUserId": userID}, nil)
+
+	context.JSON(http.StatusOK, gin.H{
+		"message": "co-host removed",
+	})
+}
@@ -0,0 +1,189 @@
+package routes
+
+import (
+	"REST-API/logging"
+	"REST-API/middleware"
+	"REST-API/models"
+	"REST-API/utils"
+	stdcontext "context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var auditExportHeader = []string{"ID", "Created At", "Actor ID", "Action", "Resource Type", "Resource ID", "Request ID", "IP", "Changes"}
+
+// records a mutation in the audit log. The change itself has already been
+// committed by the time this runs, so a failed write is logged rather than
+// failing the request, and the request deadline is ignored.
+func recordAudit(context *gin.Context, action, resourceType string, resourceID int, before, after any) {
+	logger := logging.FromContext(context.Request.Context()).With(
+		"resource_type", resourceType,
+		"resource_id", resourceID,
+	)
+	entry := models.AuditEntry{
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		RequestID:    middleware.GetRequestID(context),
+		IP:           context.ClientIP(),
+	}
+	if userID := context.GetInt("userId"); userID != 0 {
+		entry.ActorID = &userID
+	}
+
+	if before != nil || after != nil {
+		changes, err := utils.Diff(before, after)
+		if err != nil {
+			logger.Error("could not diff audit entry", "action", action, "error", err)
+		}
+		entry.Changes = changes
+	}
+
+	ctx := stdcontext.WithoutCancel(context.Request.Context())
+	if err := models.RecordAudit(ctx, &entry); err != nil {
+		logger.Error("could not record audit entry", "action", action, "error", err)
+	}
+}
+
+// getAuditLog handles GET /admin/audit
+func getAuditLog(context *gin.Context) {
+	filter, ok := parseAuditFilter(context)
+	if !ok {
+		return
+	}
+	page, limit, ok := parsePagination(context)
+	if !ok {
+		return
+	}
+
+	entries, total, err := models.GetAuditEntries(context.Request.Context(), filter, page, limit)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, paginatedResponse(entries, total, page, limit))
+}
+
+// exportAuditLog handles GET /admin/audit/export?format=csv|ndjson
+func exportAuditLog(context *gin.Context) {
+	format := context.DefaultQuery("format", "csv")
+	if format != "csv" && format != "ndjson" {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_format", "invalid format, must be csv or ndjson")
+		return
+	}
+
+	filter, ok := parseAuditFilter(context)
+	if !ok {
+		return
+	}
+
+	context.Header("Content-Disposition", `attachment; filename="audit-log.`+format+`"`)
+
+	var err error
+	if format == "ndjson" {
+		context.Header("Content-Type", "application/x-ndjson")
+		context.Status(http.StatusOK)
+
+		encoder := json.NewEncoder(context.Writer)
+		written := 0
+		err = models.ForEachAuditEntry(context.Request.Context(), filter, func(entry models.AuditEntry) error {
+			written++
+			if written%exportFlushInterval == 0 {
+				context.Writer.Flush()
+			}
+			return encoder.Encode(entry)
+		})
+	} else {
+		context.Header("Content-Type", "text/csv; charset=utf-8")
+		context.Status(http.StatusOK)
+
+		writer := csv.NewWriter(context.Writer)
+		writer.Write(auditExportHeader)
+		written := 0
+		err = models.ForEachAuditEntry(context.Request.Context(), filter, func(entry models.AuditEntry) error {
+			actorID := ""
+			if entry.ActorID != nil {
+				actorID = strconv.Itoa(*entry.ActorID)
+			}
+			changes := ""
+			if len(entry.Changes) > 0 {
+				data, err := json.Marshal(entry.Changes)
+				if err != nil {
+					return err
+				}
+				changes = string(data)
+			}
+
+			writer.Write([]string{
+				strconv.Itoa(entry.ID),
+				entry.CreatedAt.UTC().Format(time.RFC3339),
+				actorID,
+				entry.Action,
+				entry.ResourceType,
+				strconv.Itoa(entry.ResourceID),
+				entry.RequestID,
+				entry.IP,
+				changes,
+			})
+			written++
+			if written%exportFlushInterval == 0 {
+				writer.Flush()
+				context.Writer.Flush()
+			}
+			return writer.Error()
+		})
+		writer.Flush()
+	}
+
+	if err != nil {
+		// headers are already sent, so the best we can do is cut the stream short
+		logging.FromContext(context.Request.Context()).Error("audit log export failed", "error", err)
+		context.Abort()
+	}
+}
+
+// reads ?actorId=, ?action=, ?resourceType=, ?resourceId=, ?requestId=, ?from= and ?to=,
+// responding with 400 and returning ok=false if invalid
+func parseAuditFilter(context *gin.Context) (models.AuditFilter, bool) {
+	filter := models.AuditFilter{
+		Action:       context.Query("action"),
+		ResourceType: context.Query("resourceType"),
+		RequestID:    context.Query("requestId"),
+	}
+
+	ids := map[string]*int{"actorId": &filter.ActorID, "resourceId": &filter.ResourceID}
+	for param, target := range ids {
+		value := context.Query(param)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.Atoi(value)
+		if err != nil || id < 1 {
+			middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_query", "invalid "+param)
+			return filter, false
+		}
+		*target = id
+	}
+
+	times := map[string]*time.Time{"from": &filter.From, "to": &filter.To}
+	for param, target := range times {
+		value := context.Query(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_query", "invalid "+param+", must be an RFC 3339 timestamp")
+			return filter, false
+		}
+		*target = parsed
+	}
+
+	return filter, true
+}
@@ -0,0 +1,52 @@
+package routes
+
+import (
+	"REST-API/config"
+	"REST-API/db"
+	"REST-API/middleware"
+	"REST-API/models"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getBackups handles GET /admin/backups, newest first
+func getBackups(context *gin.Context) {
+	backups, err := db.ListBackups(config.Get().BackupDir)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"backups": backups,
+	})
+}
+
+// createBackup handles POST /admin/backups, taking a backup right away
+// and pruning the ones the retention policy no longer keeps
+func createBackup(context *gin.Context) {
+	backup, pruned, err := db.BackupAndPrune(context.Request.Context())
+	if errors.Is(err, db.ErrBackupInProgress) {
+		middleware.AbortWithProblem(context, http.StatusConflict, "backup_in_progress", err.Error())
+		return
+	}
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	if pruned == nil {
+		pruned = []string{}
+	}
+
+	// backups have no numeric ID, the file name is recorded instead
+	recordAudit(context, models.AuditBackupCreate, "backup", 0, nil, gin.H{"name": backup.Name, "size": backup.Size})
+
+	context.JSON(http.StatusCreated, gin.H{
+		"message": "backup created",
+		"backup":  backup,
+		"pruned":  pruned,
+	})
+}
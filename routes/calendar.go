@@ -0,0 +1,150 @@
+package routes
+
+import (
+	"REST-API/middleware"
+	"REST-API/models"
+	"REST-API/utils"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// events without an end time get a fixed length in calendars
+const defaultEventDuration = time.Hour
+
+const icsSuffix = ".ics"
+
+// getEventICS handles GET /events/:id.ics
+func getEventICS(context *gin.Context, eventID string) {
+	id, err := strconv.Atoi(strings.TrimSuffix(eventID, icsSuffix))
+	if err != nil {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_event_id", "invalid event ID")
+		return
+	}
+
+	event, err := models.GetEventByID(context.Request.Context(), id)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+	if event == nil || !canViewEvent(context, event) {
+		middleware.AbortWithError(context, models.ErrEventNotFound)
+		return
+	}
+
+	calendar := utils.BuildICalendar(event.Name, []utils.CalendarEvent{
+		toCalendarEvent(context, *event),
+	})
+
+	context.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="event-%d.ics"`, event.ID))
+	context.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(calendar))
+}
+
+// getCalendarFeed handles GET /calendar/:token.ics
+func getCalendarFeed(context *gin.Context) {
+	token := context.Param("token")
+	if !strings.HasSuffix(token, icsSuffix) {
+		middleware.AbortWithProblem(context, http.StatusNotFound, "calendar_feed_not_found", "calendar feed not found")
+		return
+	}
+	token = strings.TrimSuffix(token, icsSuffix)
+
+	feed, err := models.GetCalendarFeedByToken(context.Request.Context(), token)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+	if feed == nil {
+		middleware.AbortWithProblem(context, http.StatusNotFound, "calendar_feed_not_found", "calendar feed not found")
+		return
+	}
+
+	// let subscribed clients skip unchanged feeds
+	lastModified := feed.UpdatedAt.UTC().Truncate(time.Second)
+	if since, err := http.ParseTime(context.GetHeader("If-Modified-Since")); err == nil && !lastModified.After(since) {
+		context.Status(http.StatusNotModified)
+		return
+	}
+
+	events, err := models.GetRegisteredEvents(context.Request.Context(), feed.UserID)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	calendarEvents := make([]utils.CalendarEvent, 0, len(events))
+	for _, event := range events {
+		calendarEvents = append(calendarEvents, toCalendarEvent(context, event))
+	}
+
+	context.Header("Last-Modified", lastModified.Format(http.TimeFormat))
+	context.Data(http.StatusOK, "text/calendar; charset=utf-8",
+		[]byte(utils.BuildICalendar("My registered events", calendarEvents)))
+}
+
+// getCalendarFeedURL handles GET /calendar
+func getCalendarFeedURL(context *gin.Context) {
+	feed, err := models.GetOrCreateCalendarFeed(context.Request.Context(), context.GetInt("userId"))
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"url": calendarFeedURL(context, feed.Token),
+	})
+}
+
+// rotateCalendarToken handles POST /calendar/rotate
+func rotateCalendarToken(context *gin.Context) {
+	feed, err := models.RotateCalendarToken(context.Request.Context(), context.GetInt("userId"))
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"message": "calendar token rotated",
+		"url":     calendarFeedURL(context, feed.Token),
+	})
+}
+
+func toCalendarEvent(context *gin.Context, event models.Event) utils.CalendarEvent {
+	end := event.DateTime.Add(defaultEventDuration)
+	if event.EndDateTime != nil {
+		end = *event.EndDateTime
+	}
+
+	status := "CONFIRMED"
+	if event.Status == models.EventStatusCancelled {
+		status = "CANCELLED"
+	}
+
+	return utils.CalendarEvent{
+		UID:         fmt.Sprintf("event-%d@%s", event.ID, context.Request.Host),
+		Summary:     event.Name,
+		Description: event.Description,
+		Location:    event.Location,
+		Start:       event.DateTime,
+		End:         end,
+		Status:      status,
+		URL:         fmt.Sprintf("%s/events/%d", baseURL(context), event.ID),
+	}
+}
+
+func calendarFeedURL(context *gin.Context, token string) string {
+	return fmt.Sprintf("%s/calendar/%s%s", baseURL(context), token, icsSuffix)
+}
+
+// builds scheme://host for links, honouring TLS-terminating proxies
+func baseURL(context *gin.Context) string {
+	scheme := "http"
+	if context.Request.TLS != nil || context.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + context.Request.Host
+}
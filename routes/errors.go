@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"REST-API/middleware"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rejects the request, listing what failed validation in the problem's errors
+func abortValidationFailed(context *gin.Context, message string, errs any) {
+	middleware.AbortWithError(context, &middleware.HTTPError{
+		Status:  http.StatusBadRequest,
+		Code:    "validation_failed",
+		Message: message,
+		Errors:  errs,
+	})
+}
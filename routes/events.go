@@ -1,121 +1,148 @@
 package routes
 
 import (
+	"REST-API/config"
+	"REST-API/middleware"
 	"REST-API/models"
 	"REST-API/utils"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 func getEvents(context *gin.Context) {
-	pageStr := context.DefaultQuery("page", "1")
-	limitStr := context.DefaultQuery("limit", "10")
-
-	page, err := strconv.Atoi(pageStr)
-	if err != nil || page < 1 {
-		context.JSON(http.StatusBadRequest, gin.H{
-			"message": "invalid page number",
-		})
-		return
-	}
-
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit < 1 || limit > 100 {
-		context.JSON(http.StatusBadRequest, gin.H{
-			"message": "invalid limit, must be between 1 and 100",
-		})
+	page, limit, ok := parsePagination(context)
+	if !ok {
 		return
 	}
 
 	// Pass request context to model
 	events, total, err := models.GetAllEvents(context.Request.Context(), page, limit)
 	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": "could not fetch events!",
-			"error":   err.Error(),
-		})
+		middleware.AbortWithError(context, err)
 		return
 	}
 
-	context.JSON(http.StatusOK, gin.H{
-		"data":       events,
+	context.JSON(http.StatusOK, paginatedResponse(events, total, page, limit))
+}
+
+// reads ?page= and ?limit=, responding with 400 and returning ok=false if invalid
+func parsePagination(context *gin.Context) (page, limit int, ok bool) {
+	cfg := config.Get()
+	pageStr := context.DefaultQuery("page", "1")
+	limitStr := context.DefaultQuery("limit", strconv.Itoa(cfg.DefaultPageLimit))
+
+	page, err := strconv.Atoi(pageStr)
+	if err != nil || page < 1 {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_pagination", "invalid page number")
+		return 0, 0, false
+	}
+
+	limit, err = strconv.Atoi(limitStr)
+	if err != nil || limit < 1 || limit > cfg.MaxPageLimit {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_pagination", "invalid limit, must be between 1 and "+strconv.Itoa(cfg.MaxPageLimit))
+		return 0, 0, false
+	}
+
+	return page, limit, true
+}
+
+func paginatedResponse(data any, total, page, limit int) gin.H {
+	return gin.H{
+		"data":       data,
 		"total":      total,
 		"page":       page,
 		"limit":      limit,
 		"totalPages": (total + limit - 1) / limit, // ceiling division
-	})
+	}
 }
 
 func getEvent(context *gin.Context) {
 	eventID := context.Param("id")
+	// gin can't route /events/:id.ics separately, so the suffix is checked here
+	if strings.HasSuffix(eventID, icsSuffix) {
+		getEventICS(context, eventID)
+		return
+	}
+
 	id, err := strconv.Atoi(eventID)
 	if err != nil {
-		context.JSON(http.StatusBadRequest, gin.H{
-			"message": "Invalid event ID",
-		})
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_event_id", "invalid event ID")
 		return
 	}
 
 	// Pass request context to model
 	event, err := models.GetEventByID(context.Request.Context(), id)
 	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": "could not fetch event!",
-			"error":   err.Error(),
-		})
+		middleware.AbortWithError(context, err)
 		return
 	}
 
-	if event == nil {
-		context.JSON(http.StatusNotFound, gin.H{
-			"message": "Event not found",
-		})
+	if event == nil || !canViewEvent(context, event) {
+		middleware.AbortWithError(context, models.ErrEventNotFound)
+		return
+	}
+
+	context.Header("ETag", eventETag(event))
+	if etagMatches(context.GetHeader("If-None-Match"), eventETag(event), true) {
+		context.Status(http.StatusNotModified)
 		return
 	}
 
 	context.JSON(http.StatusOK, event)
 }
 
+// drafts are hidden from everyone but the event's organizers
+func canViewEvent(context *gin.Context, event *models.Event) bool {
+	if event.Status != models.EventStatusDraft {
+		return true
+	}
+	isOrganizer, err := isEventOrganizer(context, event)
+	return err == nil && isOrganizer
+}
+
 func createEvent(context *gin.Context) {
 	var event models.Event
 	err := context.ShouldBindJSON(&event)
 	if err != nil {
-		context.JSON(http.StatusBadRequest, gin.H{
-			"message": "could not parse request data",
-		})
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_body", "could not parse request data")
 		return
 	}
 
 	// Validate after binding
 	if validationErrors := utils.ValidateStruct(event); validationErrors != nil {
-		context.JSON(http.StatusBadRequest, gin.H{
-			"message": "validation failed",
-			"errors":  validationErrors,
-		})
+		abortValidationFailed(context, "validation failed", validationErrors)
 		return
 	}
 
 	userID, exists := context.Get("userId")
 	if !exists {
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": "could not retrieve user identity",
-		})
+		middleware.AbortWithError(context, errors.New("user identity missing from context"))
 		return
 	}
 	event.UserID = userID.(int)
 
+	// new events always start as drafts and go live through /publish
+	event.Status = models.EventStatusDraft
+	event.CancelReason = ""
+	event.CancelledAt = nil
+
 	// Pass request context to model
 	err = event.Save(context.Request.Context())
 	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": "could not create event!",
-			"error":   err.Error(),
-		})
+		middleware.AbortWithError(context, err)
 		return
 	}
 
+	recordAudit(context, models.AuditEventCreate, "event", event.ID, nil, event)
+
+	context.Header("ETag", eventETag(&event))
 	context.JSON(http.StatusCreated, gin.H{
 		"message": "Event created",
 		"event":   event,
@@ -126,25 +153,18 @@ func updateEvent(context *gin.Context) {
 	eventID := context.Param("id")
 	id, err := strconv.Atoi(eventID)
 	if err != nil {
-		context.JSON(http.StatusBadRequest, gin.H{
-			"message": "Invalid event ID",
-		})
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_event_id", "invalid event ID")
 		return
 	}
 
 	// Pass request context to model
 	existingEvent, err := models.GetEventByID(context.Request.Context(), id)
 	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": "could not fetch event!",
-			"error":   err.Error(),
-		})
+		middleware.AbortWithError(context, err)
 		return
 	}
 	if existingEvent == nil {
-		context.JSON(http.StatusNotFound, gin.H{
-			"message": "Event not found",
-		})
+		middleware.AbortWithError(context, models.ErrEventNotFound)
 		return
 	}
 
@@ -154,41 +174,158 @@ func updateEvent(context *gin.Context) {
 
 	// Only owner or admin can update
 	if existingEvent.UserID != userID && role != "admin" {
-		context.JSON(http.StatusForbidden, gin.H{
-			"message": "you are not authorized to update this event",
-		})
+		middleware.AbortWithProblem(context, http.StatusForbidden, "forbidden", "you are not authorized to update this event")
+		return
+	}
+
+	if !checkIfMatch(context, existingEvent) {
+		return
+	}
+
+	if existingEvent.Status == models.EventStatusCancelled || existingEvent.Status == models.EventStatusCompleted {
+		middleware.AbortWithProblem(context, http.StatusConflict, "event_ended", "cancelled or completed events can't be updated")
 		return
 	}
 
 	var updatedEvent models.Event
 	err = context.ShouldBindJSON(&updatedEvent)
 	if err != nil {
-		context.JSON(http.StatusBadRequest, gin.H{
-			"message": "could not parse request data",
-		})
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_body", "could not parse request data")
 		return
 	}
 
 	// Validate after binding
 	if validationErrors := utils.ValidateStruct(updatedEvent); validationErrors != nil {
-		context.JSON(http.StatusBadRequest, gin.H{
-			"message": "validation failed",
-			"errors":  validationErrors,
-		})
+		abortValidationFailed(context, "validation failed", validationErrors)
 		return
 	}
 
 	updatedEvent.ID = id
+	updatedEvent.UserID = existingEvent.UserID
+	updatedEvent.Status = existingEvent.Status
+	updatedEvent.CancelReason = ""
+	updatedEvent.CancelledAt = nil
+	updatedEvent.Version = existingEvent.Version
 	// Pass request context to model
 	err = updatedEvent.Update(context.Request.Context())
 	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": "could not update event!",
-			"error":   err.Error(),
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	recordAudit(context, models.AuditEventUpdate, "event", id, existingEvent, updatedEvent)
+
+	context.Header("ETag", eventETag(&updatedEvent))
+	context.JSON(http.StatusOK, gin.H{
+		"message": "Event updated successfully",
+		"event":   updatedEvent,
+	})
+}
+
+// JSON members a merge patch may touch, mapped to their Event field names
+var patchableEventFields = map[string]string{
+	"name":        "Name",
+	"description": "Description",
+	"location":    "Location",
+	"dateTime":    "DateTime",
+	"endDateTime": "EndDateTime",
+}
+
+// patchEvent handles PATCH /events/:id with an RFC 7396 merge patch,
+// validating only the fields it touches
+func patchEvent(context *gin.Context) {
+	mediaType, _, _ := mime.ParseMediaType(context.GetHeader("Content-Type"))
+	if mediaType != "application/merge-patch+json" && mediaType != "application/json" {
+		middleware.AbortWithProblem(context, http.StatusUnsupportedMediaType, "unsupported_media_type", "Content-Type must be application/merge-patch+json")
+		return
+	}
+
+	existingEvent, ok := loadOwnedEvent(context)
+	if !ok {
+		return
+	}
+
+	if !checkIfMatch(context, existingEvent) {
+		return
+	}
+
+	if existingEvent.Status == models.EventStatusCancelled || existingEvent.Status == models.EventStatusCompleted {
+		middleware.AbortWithProblem(context, http.StatusConflict, "event_ended", "cancelled or completed events can't be updated")
+		return
+	}
+
+	patch, err := io.ReadAll(context.Request.Body)
+	if err != nil {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_body", "could not read request data")
+		return
+	}
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &members); err != nil {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_merge_patch", "merge patch must be a JSON object")
+		return
+	}
+
+	// an empty patch changes nothing, so don't create a revision for it
+	if len(members) == 0 {
+		context.Header("ETag", eventETag(existingEvent))
+		context.JSON(http.StatusOK, gin.H{
+			"message": "Event updated successfully",
+			"event":   existingEvent,
 		})
 		return
 	}
 
+	touched := make([]string, 0, len(members))
+	for member := range members {
+		field, ok := patchableEventFields[member]
+		if !ok {
+			middleware.AbortWithProblem(context, http.StatusBadRequest, "field_not_patchable", "field can't be patched: "+member)
+			return
+		}
+		touched = append(touched, field)
+	}
+
+	current, err := json.Marshal(existingEvent.Details())
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+	merged, err := utils.MergePatch(current, patch)
+	if err != nil {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_merge_patch", err.Error())
+		return
+	}
+
+	var details models.EventDetails
+	if err := json.Unmarshal(merged, &details); err != nil {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_body", "could not parse request data")
+		return
+	}
+
+	updatedEvent := *existingEvent
+	updatedEvent.SetDetails(details)
+
+	// moving the start can push it past an untouched end time
+	if _, ok := members["dateTime"]; ok && updatedEvent.EndDateTime != nil {
+		if _, ok := members["endDateTime"]; !ok {
+			touched = append(touched, "EndDateTime")
+		}
+	}
+	if validationErrors := utils.ValidateStructPartial(updatedEvent, touched...); validationErrors != nil {
+		abortValidationFailed(context, "validation failed", validationErrors)
+		return
+	}
+
+	err = updatedEvent.Update(context.Request.Context())
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	recordAudit(context, models.AuditEventUpdate, "event", updatedEvent.ID, existingEvent, updatedEvent)
+
+	context.Header("ETag", eventETag(&updatedEvent))
 	context.JSON(http.StatusOK, gin.H{
 		"message": "Event updated successfully",
 		"event":   updatedEvent,
@@ -199,25 +336,18 @@ func deleteEvent(context *gin.Context) {
 	eventID := context.Param("id")
 	id, err := strconv.Atoi(eventID)
 	if err != nil {
-		context.JSON(http.StatusBadRequest, gin.H{
-			"message": "Invalid event ID",
-		})
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_event_id", "invalid event ID")
 		return
 	}
 
 	// Pass request context to model
 	existingEvent, err := models.GetEventByID(context.Request.Context(), id)
 	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": "could not fetch event!",
-			"error":   err.Error(),
-		})
+		middleware.AbortWithError(context, err)
 		return
 	}
 	if existingEvent == nil {
-		context.JSON(http.StatusNotFound, gin.H{
-			"message": "Event not found",
-		})
+		middleware.AbortWithError(context, models.ErrEventNotFound)
 		return
 	}
 
@@ -227,22 +357,35 @@ func deleteEvent(context *gin.Context) {
 
 	// Only owner or admin can delete
 	if existingEvent.UserID != userID && role != "admin" {
-		context.JSON(http.StatusForbidden, gin.H{
-			"message": "you are not authorized to delete this event",
-		})
+		middleware.AbortWithProblem(context, http.StatusForbidden, "forbidden", "you are not authorized to delete this event")
+		return
+	}
+
+	if !checkIfMatch(context, existingEvent) {
+		return
+	}
+
+	// deleting hides the event from attendees without a word, while cancelling
+	// notifies them and keeps it readable so they can see why
+	registrations, err := models.CountRegistrations(context.Request.Context(), id)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+	if registrations > 0 {
+		middleware.AbortWithProblem(context, http.StatusConflict, "event_has_registrations", "event has registrations, cancel it instead")
 		return
 	}
 
 	// Pass request context to model
 	err = existingEvent.Delete(context.Request.Context())
 	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": "could not delete event!",
-			"error":   err.Error(),
-		})
+		middleware.AbortWithError(context, err)
 		return
 	}
 
+	recordAudit(context, models.AuditEventDelete, "event", id, existingEvent, nil)
+
 	context.JSON(http.StatusOK, gin.H{
 		"message": "Event deleted successfully",
 	})
@@ -0,0 +1,80 @@
+package routes
+
+import (
+	"REST-API/db"
+	"REST-API/version"
+	stdcontext "context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const readinessTimeout = 2 * time.Second
+
+// set once shutdown starts so load balancers stop sending traffic
+var draining atomic.Bool
+
+// makes /readyz fail from now on, called as soon as a shutdown signal arrives
+func BeginDrain() {
+	draining.Store(true)
+}
+
+// healthz handles GET /healthz, the process is up and serving
+func healthz(context *gin.Context) {
+	context.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+	})
+}
+
+// readyz handles GET /readyz, the instance can take traffic
+func readyz(context *gin.Context) {
+	checks := gin.H{}
+	ready := true
+
+	if draining.Load() {
+		checks["shutdown"] = "draining"
+		ready = false
+	}
+
+	ctx, cancel := stdcontext.WithTimeout(context.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	// the reader pool, a long write queue shouldn't take the instance out of rotation
+	if err := db.ReadDB.PingContext(ctx); err != nil {
+		checks["database"] = "unreachable"
+		ready = false
+	} else {
+		checks["database"] = "ok"
+	}
+
+	current, err := db.SchemaVersion(ctx)
+	switch {
+	case err != nil:
+		checks["migrations"] = "unknown"
+		ready = false
+	case current != db.LatestSchemaVersion():
+		checks["migrations"] = gin.H{"current": current, "expected": db.LatestSchemaVersion()}
+		ready = false
+	default:
+		checks["migrations"] = "ok"
+	}
+
+	status := http.StatusOK
+	result := "ready"
+	if !ready {
+		status = http.StatusServiceUnavailable
+		result = "not ready"
+	}
+
+	context.JSON(status, gin.H{
+		"status": result,
+		"checks": checks,
+	})
+}
+
+// getVersion handles GET /version
+func getVersion(context *gin.Context) {
+	context.JSON(http.StatusOK, version.Get())
+}
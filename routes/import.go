@@ -0,0 +1,81 @@
+package routes
+
+import (
+	"REST-API/middleware"
+	"REST-API/models"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxImportSize = 5 << 20 // 5 MB upload limit
+const maxImportRows = 1000
+
+// importEvents handles POST /events/import
+func importEvents(context *gin.Context) {
+	dryRun, err := strconv.ParseBool(context.DefaultQuery("dryRun", "false"))
+	if err != nil {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_query", "invalid dryRun value")
+		return
+	}
+
+	context.Request.Body = http.MaxBytesReader(context.Writer, context.Request.Body, maxImportSize)
+	fileHeader, err := context.FormFile("file")
+	if err != nil {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "file_required", "a file upload named \"file\" is required")
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_upload", "could not read uploaded file")
+		return
+	}
+	defer file.Close()
+
+	events, rowErrors, err := models.ParseImport(file, fileHeader.Filename, context.Query("format"), context.GetInt("userId"))
+	if errors.Is(err, models.ErrUnsupportedImportFormat) {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "unsupported_file_format", err.Error())
+		return
+	}
+	if err != nil {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_file", err.Error())
+		return
+	}
+	if len(events) > maxImportRows {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "too_many_rows", "too many rows, at most "+strconv.Itoa(maxImportRows)+" events can be imported at once")
+		return
+	}
+	if len(rowErrors) > 0 {
+		abortValidationFailed(context, "validation failed, no events were imported", rowErrors)
+		return
+	}
+
+	if dryRun {
+		context.JSON(http.StatusOK, gin.H{
+			"message": "dry run successful, no events were imported",
+			"dryRun":  true,
+			"total":   len(events),
+		})
+		return
+	}
+
+	// all-or-nothing: a single failed insert rolls back the whole import
+	if err := models.SaveEvents(context.Request.Context(), events); err != nil {
+		middleware.AbortWithError(context, fmt.Errorf("importing events: %w", err))
+		return
+	}
+
+	for _, event := range events {
+		recordAudit(context, models.AuditEventCreate, "event", event.ID, nil, event)
+	}
+
+	context.JSON(http.StatusCreated, gin.H{
+		"message":  "events imported",
+		"imported": len(events),
+		"events":   events,
+	})
+}
@@ -0,0 +1,52 @@
+package routes
+
+import (
+	"REST-API/middleware"
+	"REST-API/models"
+	"REST-API/scheduler"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// the background jobs, set by RegisterRoutes
+var jobScheduler *scheduler.Scheduler
+
+// getJobs handles GET /admin/jobs, with each job's schedule and recent runs
+func getJobs(context *gin.Context) {
+	context.JSON(http.StatusOK, gin.H{
+		"jobs": jobScheduler.Jobs(),
+	})
+}
+
+// runJob handles POST /admin/jobs/:name/run, starting the job now rather
+// than waiting for its schedule. It runs in the background, so the response
+// doesn't wait for it to finish.
+func runJob(context *gin.Context) {
+	name := context.Param("name")
+
+	err := jobScheduler.RunNow(name)
+	switch {
+	case errors.Is(err, scheduler.ErrJobNotFound):
+		middleware.AbortWithProblem(context, http.StatusNotFound, "job_not_found", "no job named "+name)
+		return
+	case errors.Is(err, scheduler.ErrJobRunning):
+		middleware.AbortWithProblem(context, http.StatusConflict, "job_running", err.Error())
+		return
+	case errors.Is(err, scheduler.ErrStopped):
+		middleware.AbortWithProblem(context, http.StatusServiceUnavailable, "shutting_down", err.Error())
+		return
+	case err != nil:
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	// jobs have no numeric ID, the name is recorded instead
+	recordAudit(context, models.AuditJobRun, "job", 0, nil, gin.H{"name": name})
+
+	context.JSON(http.StatusAccepted, gin.H{
+		"message": "job started",
+		"job":     name,
+	})
+}
@@ -0,0 +1,61 @@
+package routes
+
+import (
+	"REST-API/middleware"
+	"REST-API/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// publishEvent handles POST /events/:id/publish
+func publishEvent(context *gin.Context) {
+	event, ok := loadOwnedEvent(context)
+	if !ok {
+		return
+	}
+
+	before := *event
+	err := event.Publish(context.Request.Context())
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	recordAudit(context, models.AuditEventPublish, "event", event.ID, before, event)
+
+	context.JSON(http.StatusOK, gin.H{
+		"message": "Event published",
+		"event":   event,
+	})
+}
+
+// cancelEvent handles POST /events/:id/cancel
+func cancelEvent(context *gin.Context) {
+	event, ok := loadOwnedEvent(context)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Reason string `json:"reason" binding:"required,min=3,max=500"`
+	}
+	if err := context.ShouldBindJSON(&request); err != nil {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_cancel_reason", "a cancellation reason of 3 to 500 characters is required")
+		return
+	}
+
+	before := *event
+	err := event.Cancel(context.Request.Context(), request.Reason)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	recordAudit(context, models.AuditEventCancel, "event", event.ID, before, event)
+
+	context.JSON(http.StatusOK, gin.H{
+		"message": "Event cancelled, registrants have been notified",
+		"event":   event,
+	})
+}
@@ -0,0 +1,121 @@
+package routes
+
+import (
+	"REST-API/middleware"
+	"REST-API/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getMe handles GET /me
+func getMe(context *gin.Context) {
+	user, err := models.GetUserByID(context.Request.Context(), context.GetInt("userId"))
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+	if user == nil {
+		middleware.AbortWithError(context, models.ErrUserNotFound)
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"id":    user.ID,
+		"email": user.Email,
+		"role":  user.Role,
+	})
+}
+
+// getMyEvents handles GET /me/events
+func getMyEvents(context *gin.Context) {
+	filter, ok := parseTimeFilter(context)
+	if !ok {
+		return
+	}
+	page, limit, ok := parsePagination(context)
+	if !ok {
+		return
+	}
+
+	events, total, err := models.GetEventsByUser(context.Request.Context(), context.GetInt("userId"), filter, page, limit)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, paginatedResponse(events, total, page, limit))
+}
+
+// getMyRegistrations handles GET /me/registrations
+func getMyRegistrations(context *gin.Context) {
+	filter, ok := parseTimeFilter(context)
+	if !ok {
+		return
+	}
+	page, limit, ok := parsePagination(context)
+	if !ok {
+		return
+	}
+
+	registrations, total, err := models.GetUserRegistrations(context.Request.Context(), context.GetInt("userId"), filter, page, limit)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, paginatedResponse(registrations, total, page, limit))
+}
+
+// reads ?when=upcoming|past, responding with 400 and returning ok=false if invalid
+func parseTimeFilter(context *gin.Context) (string, bool) {
+	filter := context.Query("when")
+	switch filter {
+	case models.TimeFilterAll, models.TimeFilterUpcoming, models.TimeFilterPast:
+		return filter, true
+	default:
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_query", "invalid when filter, must be upcoming or past")
+		return "", false
+	}
+}
+
+// getMyNotifications handles GET /me/notifications
+func getMyNotifications(context *gin.Context) {
+	unreadOnly, err := strconv.ParseBool(context.DefaultQuery("unread", "false"))
+	if err != nil {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_query", "invalid unread value")
+		return
+	}
+	page, limit, ok := parsePagination(context)
+	if !ok {
+		return
+	}
+
+	notifications, total, err := models.GetUserNotifications(context.Request.Context(), context.GetInt("userId"), unreadOnly, page, limit)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, paginatedResponse(notifications, total, page, limit))
+}
+
+// markNotificationRead handles POST /me/notifications/:id/read
+func markNotificationRead(context *gin.Context) {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_notification_id", "invalid notification ID")
+		return
+	}
+
+	err = models.MarkNotificationRead(context.Request.Context(), id, context.GetInt("userId"))
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"message": "notification marked as read",
+	})
+}
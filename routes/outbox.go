@@ -0,0 +1,58 @@
+package routes
+
+import (
+	"REST-API/middleware"
+	"REST-API/models"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+var outboxStatuses = []string{models.OutboxStatusDead, models.OutboxStatusPending, models.OutboxStatusProcessing, models.OutboxStatusDone}
+
+// getOutboxMessages handles GET /admin/outbox?status=dead|pending|processing|done,
+// newest first. Dead-lettered messages are shown unless another status is asked for.
+func getOutboxMessages(context *gin.Context) {
+	status := context.DefaultQuery("status", models.OutboxStatusDead)
+	if !slices.Contains(outboxStatuses, status) {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_status", "invalid status, must be dead, pending, processing or done")
+		return
+	}
+	page, limit, ok := parsePagination(context)
+	if !ok {
+		return
+	}
+
+	messages, total, err := models.GetOutboxMessages(context.Request.Context(), status, page, limit)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, paginatedResponse(messages, total, page, limit))
+}
+
+// retryOutboxMessage handles POST /admin/outbox/:id/retry, giving a
+// dead-lettered message a fresh set of attempts
+func retryOutboxMessage(context *gin.Context) {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_outbox_id", "invalid outbox message ID")
+		return
+	}
+
+	message, err := models.RetryOutboxMessage(context.Request.Context(), id)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	recordAudit(context, models.AuditOutboxRetry, "outbox", id, nil, nil)
+
+	context.JSON(http.StatusOK, gin.H{
+		"message": "outbox message queued for retry",
+		"outbox":  message,
+	})
+}
@@ -0,0 +1,51 @@
+package routes
+
+import (
+	"REST-API/middleware"
+	"REST-API/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// events are versioned, so the version alone identifies a representation
+func eventETag(event *models.Event) string {
+	return `"` + strconv.Itoa(event.Version) + `"`
+}
+
+// reports whether an If-Match or If-None-Match header lists etag. If-Match
+// needs the strong comparison, so a weak validator never matches it, while
+// If-None-Match compares weak validators by their opaque tag.
+func etagMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// requires an If-Match header matching the event's current version before it
+// is changed, so concurrent edits can't silently overwrite each other.
+// Responds with 428 or 412 and returns false otherwise.
+func checkIfMatch(context *gin.Context, event *models.Event) bool {
+	ifMatch := context.GetHeader("If-Match")
+	if ifMatch == "" {
+		middleware.AbortWithProblem(context, http.StatusPreconditionRequired, "if_match_required", "If-Match header with the event's ETag is required")
+		return false
+	}
+
+	if !etagMatches(ifMatch, eventETag(event), false) {
+		context.Header("ETag", eventETag(event))
+		middleware.AbortWithProblem(context, http.StatusPreconditionFailed, "event_modified", "event was modified by someone else, fetch it again and retry")
+		return false
+	}
+
+	return true
+}
@@ -1,6 +1,8 @@
 package routes
 
 import (
+	"REST-API/metrics"
+	"REST-API/middleware"
 	"REST-API/models"
 	"net/http"
 	"strconv"
@@ -13,9 +15,7 @@ func registerForEvent(context *gin.Context) {
 
 	eventID, err := strconv.Atoi(context.Param("id"))
 	if err != nil {
-		context.JSON(http.StatusBadRequest, gin.H{
-			"message": "invalid event ID",
-		})
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_event_id", "invalid event ID")
 		return
 	}
 
@@ -26,24 +26,13 @@ func registerForEvent(context *gin.Context) {
 
 	err = registration.Save(context.Request.Context())
 	if err != nil {
-		if err.Error() == "event not found" {
-			context.JSON(http.StatusNotFound, gin.H{
-				"message": "event not found",
-			})
-			return
-		}
-		if err.Error() == "already registered for this event" {
-			context.JSON(http.StatusConflict, gin.H{
-				"message": "you are already registered for this event",
-			})
-			return
-		}
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": "could not register for event",
-		})
+		middleware.AbortWithError(context, err)
 		return
 	}
 
+	recordAudit(context, models.AuditEventRegister, "event", eventID, nil, nil)
+	metrics.Registrations.WithLabelValues("created").Inc()
+
 	context.JSON(http.StatusCreated, gin.H{
 		"message": "successfully registered for event",
 		"registration": gin.H{
@@ -59,9 +48,7 @@ func cancelRegistration(context *gin.Context) {
 
 	eventID, err := strconv.Atoi(context.Param("id"))
 	if err != nil {
-		context.JSON(http.StatusBadRequest, gin.H{
-			"message": "invalid event ID",
-		})
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_event_id", "invalid event ID")
 		return
 	}
 
@@ -72,24 +59,13 @@ func cancelRegistration(context *gin.Context) {
 
 	err = registration.Cancel(context.Request.Context())
 	if err != nil {
-		if err.Error() == "event not found" {
-			context.JSON(http.StatusNotFound, gin.H{
-				"message": "event not found",
-			})
-			return
-		}
-		if err.Error() == "you are not registered for this event" {
-			context.JSON(http.StatusBadRequest, gin.H{
-				"message": "you are not registered for this event",
-			})
-			return
-		}
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": "could not cancel registration",
-		})
+		middleware.AbortWithError(context, err)
 		return
 	}
 
+	recordAudit(context, models.AuditEventUnregister, "event", eventID, nil, nil)
+	metrics.Registrations.WithLabelValues("cancelled").Inc()
+
 	context.JSON(http.StatusOK, gin.H{
 		"message": "registration cancelled successfully",
 	})
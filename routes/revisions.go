@@ -0,0 +1,130 @@
+package routes
+
+import (
+	"REST-API/middleware"
+	"REST-API/models"
+	"REST-API/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getEventRevisions handles GET /events/:id/revisions
+func getEventRevisions(context *gin.Context) {
+	event, ok := loadManagedEvent(context)
+	if !ok {
+		return
+	}
+
+	page, limit, ok := parsePagination(context)
+	if !ok {
+		return
+	}
+
+	revisions, total, err := models.GetEventRevisions(context.Request.Context(), event.ID, page, limit)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, paginatedResponse(revisions, total, page, limit))
+}
+
+// diffEventRevisions handles GET /events/:id/revisions/diff?from=&to=
+// a missing ?to= compares against the event as it is now
+func diffEventRevisions(context *gin.Context) {
+	event, ok := loadManagedEvent(context)
+	if !ok {
+		return
+	}
+
+	from, ok := loadRevisionDetails(context, event, context.Query("from"))
+	if !ok {
+		return
+	}
+	to, ok := loadRevisionDetails(context, event, context.DefaultQuery("to", "current"))
+	if !ok {
+		return
+	}
+
+	changes, err := utils.Diff(from, to)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"from":    context.Query("from"),
+		"to":      context.DefaultQuery("to", "current"),
+		"changes": changes,
+	})
+}
+
+// restoreEventRevision handles POST /events/:id/revisions/:rev/restore
+func restoreEventRevision(context *gin.Context) {
+	event, ok := loadOwnedEvent(context)
+	if !ok {
+		return
+	}
+
+	if event.Status == models.EventStatusCancelled || event.Status == models.EventStatusCompleted {
+		middleware.AbortWithProblem(context, http.StatusConflict, "event_ended", "cancelled or completed events can't be updated")
+		return
+	}
+
+	details, ok := loadRevisionDetails(context, event, context.Param("rev"))
+	if !ok {
+		return
+	}
+
+	restored := *event
+	restored.SetDetails(details)
+
+	// an old revision may no longer be valid, e.g. its date has passed
+	if validationErrors := utils.ValidateStruct(restored); validationErrors != nil {
+		abortValidationFailed(context, "revision can't be restored, validation failed", validationErrors)
+		return
+	}
+
+	// updating snapshots the current details, so a restore can itself be undone
+	err := restored.Update(context.Request.Context())
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	recordAudit(context, models.AuditEventUpdate, "event", event.ID, event, restored)
+
+	context.Header("ETag", eventETag(&restored))
+	context.JSON(http.StatusOK, gin.H{
+		"message": "revision restored",
+		"event":   restored,
+	})
+}
+
+// resolves a revision number, or "current" for the event's live details,
+// responding with 400/404 and returning ok=false if it can't
+func loadRevisionDetails(context *gin.Context, event *models.Event, rev string) (models.EventDetails, bool) {
+	if rev == "current" {
+		return event.Details(), true
+	}
+
+	number, err := strconv.Atoi(rev)
+	if err != nil || number < 1 {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_revision", "invalid revision, must be a revision number or \"current\"")
+		return models.EventDetails{}, false
+	}
+
+	revision, err := models.GetEventRevision(context.Request.Context(), event.ID, number)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return models.EventDetails{}, false
+	}
+	if revision == nil {
+		middleware.AbortWithProblem(context, http.StatusNotFound, "revision_not_found", "revision not found")
+		return models.EventDetails{}, false
+	}
+
+	return revision.EventDetails, true
+}
@@ -1,12 +1,40 @@
 package routes
 
 import (
+	"REST-API/config"
+	"REST-API/metrics"
 	"REST-API/middleware"
+	"REST-API/scheduler"
+	"REST-API/webhooks"
 
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterRoutes(server *gin.Engine) {
+// routes that need longer than REQUEST_TIMEOUT, keyed like the routes below
+func TimeoutOverrides() middleware.TimeoutOverrides {
+	return middleware.TimeoutOverrides{
+		"GET /events/:id/registrations/export": config.Get().ExportTimeout,
+		"GET /admin/audit/export":              config.Get().ExportTimeout,
+		"POST /events/import":                  config.Get().ExportTimeout,
+		"POST /admin/backups":                  config.Get().ExportTimeout,
+		// streams stay open until the client or server goes away
+		"GET /events/stream":     0,
+		"GET /events/:id/stream": 0,
+	}
+}
+
+func RegisterRoutes(server *gin.Engine, jobs *scheduler.Scheduler, sender *webhooks.Sender) {
+	jobScheduler = jobs
+	webhookSender = sender
+
+	// Orchestrator probes and build metadata
+	server.GET("/healthz", healthz)
+	server.GET("/readyz", readyz)
+	server.GET("/version", getVersion)
+
+	// Prometheus scrape endpoint
+	server.GET("/metrics", gin.WrapH(metrics.Handler()))
+
 	// PUBLIC ROUTES (no auth required)
 	server.POST("/signup", signup)
 	server.POST("/login", login)
@@ -15,29 +43,98 @@ func RegisterRoutes(server *gin.Engine) {
 
 	// SEMI-PUBLIC ROUTES (anyone can view)
 	server.GET("/events", getEvents)
-	server.GET("/events/:id", getEvent)
+	// drafts are only shown to their organizers, so identify them if a token is sent
+	server.GET("/events/:id", middleware.OptionalAuthenticate, getEvent) // also serves /events/:id.ics
+
+	// live changes over Server-Sent Events, drafts again only to their organizers
+	server.GET("/events/stream", streamEvents)
+	server.GET("/events/:id/stream", middleware.OptionalAuthenticate, streamEvent)
+
+	// secret-token calendar subscription feed
+	server.GET("/calendar/:token", getCalendarFeed)
 
 	// PROTECTED ROUTES (authenticated users only)
 	authenticated := server.Group("/")
-	authenticated.Use(middleware.Authenticate)
+	// retried POSTs carrying an Idempotency-Key get the original response,
+	// bodies are capped at the largest any of these routes accepts
+	authenticated.Use(middleware.Authenticate, middleware.Idempotency(config.Get().IdempotencyTTL, maxImportSize))
 	{
 		// Any logged-in user can create events
 		authenticated.POST("/events", createEvent)
+		authenticated.POST("/events/import", importEvents)
 
 		// Only owner or admin can update/delete (checked in handler)
 		authenticated.PUT("/events/:id", updateEvent)
+		authenticated.PATCH("/events/:id", patchEvent)
 		authenticated.DELETE("/events/:id", deleteEvent)
 
+		// Lifecycle transitions, owner or admin only
+		authenticated.POST("/events/:id/publish", publishEvent)
+		authenticated.POST("/events/:id/cancel", cancelEvent)
+
 		// Any logged-in user can register for events
 		authenticated.POST("/events/:id/register", registerForEvent)
 		authenticated.DELETE("/events/:id/register", cancelRegistration)
+
+		// Owner, co-hosts or admin can see who registered
+		authenticated.GET("/events/:id/registrations", getEventRegistrations)
+		authenticated.GET("/events/:id/registrations/export", exportEventRegistrations)
+
+		// Organizers can review earlier versions, owner or admin can roll back
+		authenticated.GET("/events/:id/revisions", getEventRevisions)
+		authenticated.GET("/events/:id/revisions/diff", diffEventRevisions)
+		authenticated.POST("/events/:id/revisions/:rev/restore", restoreEventRevision)
+
+		// Only owner or admin can manage co-hosts
+		authenticated.POST("/events/:id/cohosts", addCoHost)
+		authenticated.DELETE("/events/:id/cohosts/:userId", removeCoHost)
+
+		// The logged-in user's profile, events and registrations
+		authenticated.GET("/me", getMe)
+		authenticated.GET("/me/events", getMyEvents)
+		authenticated.GET("/me/registrations", getMyRegistrations)
+		authenticated.GET("/me/notifications", getMyNotifications)
+		authenticated.POST("/me/notifications/:id/read", markNotificationRead)
+
+		// Personal calendar feed URL for subscribing from Google/Outlook
+		authenticated.GET("/calendar", getCalendarFeedURL)
+		authenticated.POST("/calendar/rotate", rotateCalendarToken)
+
+		// Signed notifications of event changes, owner or admin only
+		authenticated.POST("/webhooks", createWebhook)
+		authenticated.GET("/webhooks", getWebhooks)
+		authenticated.GET("/webhooks/:id", getWebhook)
+		authenticated.PUT("/webhooks/:id", updateWebhook)
+		authenticated.DELETE("/webhooks/:id", deleteWebhook)
+		authenticated.GET("/webhooks/:id/deliveries", getWebhookDeliveries)
+		authenticated.POST("/webhooks/:id/test", testWebhook)
 	}
 
-	// ADMIN-ONLY ROUTES (for future admin features)
+	// ADMIN-ONLY ROUTES
 	admin := server.Group("/admin")
 	admin.Use(middleware.Authenticate, middleware.RequireAdmin)
 	{
-		// Future: admin.GET("/users", getAllUsers)
-		// Future: admin.DELETE("/users/:id", deleteUser)
+		// Soft-deleted events and users can be restored until they're purged
+		admin.GET("/events/deleted", getDeletedEvents)
+		admin.POST("/events/:id/restore", restoreEvent)
+		admin.DELETE("/users/:id", deleteUser)
+		admin.POST("/users/:id/restore", restoreUser)
+		admin.PUT("/users/:id/role", setUserRole)
+
+		// Append-only record of every mutating action
+		admin.GET("/audit", getAuditLog)
+		admin.GET("/audit/export", exportAuditLog)
+
+		// On-demand database backups, restores are done with the CLI
+		admin.GET("/backups", getBackups)
+		admin.POST("/backups", createBackup)
+
+		// Background maintenance jobs and their recent runs
+		admin.GET("/jobs", getJobs)
+		admin.POST("/jobs/:name/run", runJob)
+
+		// Side effects that ran out of retries can be inspected and retried
+		admin.GET("/outbox", getOutboxMessages)
+		admin.POST("/outbox/:id/retry", retryOutboxMessage)
 	}
 }
@@ -0,0 +1,148 @@
+package routes
+
+import (
+	"REST-API/config"
+	"REST-API/live"
+	"REST-API/middleware"
+	"REST-API/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// how long EventSource clients wait before reconnecting
+const streamRetry = 3 * time.Second
+
+// streamEvent handles GET /events/:id/stream, pushing changes to the event
+// and its registration count. A fresh connection, or one whose Last-Event-ID
+// can't be resumed from, starts with the current state.
+func streamEvent(context *gin.Context) {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_event_id", "invalid event ID")
+		return
+	}
+
+	// subscribed before reading the current state so nothing in between is missed
+	subscription, ok := subscribe(context, func(message live.Message) bool {
+		return message.EventID == id
+	})
+	if !ok {
+		return
+	}
+	defer subscription.Close()
+
+	ctx := context.Request.Context()
+	event, err := models.GetEventByID(ctx, id)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+	if event == nil || !canViewEvent(context, event) {
+		middleware.AbortWithError(context, models.ErrEventNotFound)
+		return
+	}
+
+	count, err := models.CountRegistrations(ctx, id)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	startStream(context)
+	if !subscription.Resumed {
+		eventData, _ := json.Marshal(event)
+		countData, _ := json.Marshal(models.RegistrationCount{EventID: id, Registrations: count})
+		writeStreamMessage(context, live.Message{ID: subscription.LastID, Type: "event.snapshot", Data: eventData})
+		writeStreamMessage(context, live.Message{ID: subscription.LastID, Type: models.LiveRegistrations, Data: countData})
+	}
+
+	runStream(context, subscription, func(message live.Message) bool {
+		// nothing more will happen to a deleted event
+		return message.Type != models.LiveEventDeleted
+	})
+}
+
+// streamEvents handles GET /events/stream, pushing changes to every event
+// but drafts. A client whose Last-Event-ID can't be resumed from is sent a
+// reset and should reload the events it shows.
+func streamEvents(context *gin.Context) {
+	subscription, ok := subscribe(context, func(message live.Message) bool {
+		return message.Public
+	})
+	if !ok {
+		return
+	}
+	defer subscription.Close()
+
+	startStream(context)
+	if context.GetHeader("Last-Event-ID") != "" && !subscription.Resumed {
+		writeStreamMessage(context, live.Message{ID: subscription.LastID, Type: "reset", Data: []byte("{}")})
+	}
+
+	runStream(context, subscription, func(live.Message) bool { return true })
+}
+
+func subscribe(context *gin.Context, filter func(live.Message) bool) (*live.Subscription, bool) {
+	subscription, err := live.Subscribe(filter, context.GetHeader("Last-Event-ID"))
+	if err != nil {
+		middleware.AbortWithProblem(context, http.StatusServiceUnavailable, "shutting_down", err.Error())
+		return nil, false
+	}
+	return subscription, true
+}
+
+func startStream(context *gin.Context) {
+	header := context.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	// stops nginx from buffering the stream
+	header.Set("X-Accel-Buffering", "no")
+	context.Status(http.StatusOK)
+
+	fmt.Fprintf(context.Writer, "retry: %d\n\n", streamRetry.Milliseconds())
+	context.Writer.Flush()
+}
+
+// writes the replayed and then the live messages until the client goes
+// away, the hub closes the subscription, or more returns false
+func runStream(context *gin.Context, subscription *live.Subscription, more func(live.Message) bool) {
+	for _, message := range subscription.Replay {
+		writeStreamMessage(context, message)
+		if !more(message) {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(config.Get().StreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case message, ok := <-subscription.Messages:
+			// shutting down, or the client fell behind and can resume
+			if !ok {
+				return
+			}
+			writeStreamMessage(context, message)
+			if !more(message) {
+				return
+			}
+		case <-heartbeat.C:
+			// a comment, ignored by clients but keeps proxies from timing out
+			fmt.Fprint(context.Writer, ": heartbeat\n\n")
+			context.Writer.Flush()
+		case <-context.Request.Context().Done():
+			return
+		}
+	}
+}
+
+func writeStreamMessage(context *gin.Context, message live.Message) {
+	fmt.Fprintf(context.Writer, "id: %d\nevent: %s\ndata: %s\n\n", message.ID, message.Type, message.Data)
+	context.Writer.Flush()
+}
@@ -1,9 +1,10 @@
 package routes
 
 import (
+	"REST-API/metrics"
+	"REST-API/middleware"
 	"REST-API/models"
 	"REST-API/utils"
-	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -13,37 +14,23 @@ func signup(context *gin.Context) {
 	var user models.User
 	err := context.ShouldBindJSON(&user)
 	if err != nil {
-		context.JSON(http.StatusBadRequest, gin.H{
-			"message": "could not parse request data",
-		})
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_body", "could not parse request data")
 		return
 	}
 
 	if validationErrors := utils.ValidateStruct(user); validationErrors != nil {
-		context.JSON(http.StatusBadRequest, gin.H{
-			"message": "validation failed",
-			"errors":  validationErrors,
-		})
+		abortValidationFailed(context, "validation failed", validationErrors)
 		return
 	}
 
 	err = user.Save(context.Request.Context())
 	if err != nil {
-		if err.Error() == "email already registered" {
-			context.JSON(http.StatusConflict, gin.H{
-				"message": "email already registered",
-			})
-			return
-		}
-		// ADD THIS LINE TO SEE THE ACTUAL ERROR
-		fmt.Println("[DEBUG] Error creating user:", err)
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": "could not create user",
-			"error":   err.Error(), // ALSO ADD THIS
-		})
+		middleware.AbortWithError(context, err)
 		return
 	}
 
+	recordAudit(context, models.AuditUserCreate, "user", user.ID, nil, gin.H{"email": user.Email, "role": user.Role})
+
 	context.JSON(http.StatusCreated, gin.H{
 		"message": "user created successfully",
 		"user": gin.H{
@@ -57,55 +44,45 @@ func login(context *gin.Context) {
 	var user models.User
 	err := context.ShouldBindJSON(&user)
 	if err != nil {
-		context.JSON(http.StatusBadRequest, gin.H{
-			"message": "could not parse request data",
-		})
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_body", "could not parse request data")
 		return
 	}
 
 	if validationErrors := utils.ValidateStruct(user); validationErrors != nil {
-		context.JSON(http.StatusBadRequest, gin.H{
-			"message": "validation failed",
-			"errors":  validationErrors,
-		})
+		abortValidationFailed(context, "validation failed", validationErrors)
 		return
 	}
 
 	err = user.ValidateCredentials(context.Request.Context())
 	if err != nil {
-		context.JSON(http.StatusUnauthorized, gin.H{
-			"message": "invalid credentials",
-		})
+		metrics.Logins.WithLabelValues("failure").Inc()
+		middleware.AbortWithError(context, err)
 		return
 	}
 
 	// Generate access token (JWT)
 	accessToken, err := utils.GenerateToken(user.Email, user.ID, user.Role)
 	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": "could not generate access token",
-		})
+		middleware.AbortWithError(context, err)
 		return
 	}
 
 	// Generate refresh token
 	refreshToken, err := utils.GenerateRefreshToken()
 	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": "could not generate refresh token",
-		})
+		middleware.AbortWithError(context, err)
 		return
 	}
 
 	// Save refresh token to database
 	err = user.SaveRefreshToken(context.Request.Context(), refreshToken)
 	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": "could not save refresh token",
-		})
+		middleware.AbortWithError(context, err)
 		return
 	}
 
+	metrics.Logins.WithLabelValues("success").Inc()
+
 	// Return both tokens
 	context.JSON(http.StatusOK, gin.H{
 		"message":       "login successful",
@@ -122,36 +99,28 @@ func refreshToken(context *gin.Context) {
 
 	err := context.ShouldBindJSON(&request)
 	if err != nil {
-		context.JSON(http.StatusBadRequest, gin.H{
-			"message": "refresh token required",
-		})
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "refresh_token_required", "refresh token required")
 		return
 	}
 
 	// Validate the refresh token and get the associated user
 	user, err := models.ValidateRefreshToken(context.Request.Context(), request.RefreshToken)
 	if err != nil {
-		context.JSON(http.StatusUnauthorized, gin.H{
-			"message": err.Error(),
-		})
+		middleware.AbortWithError(context, err)
 		return
 	}
 
 	// Generate new access token
 	newAccessToken, err := utils.GenerateToken(user.Email, user.ID, user.Role)
 	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": "could not generate access token",
-		})
+		middleware.AbortWithError(context, err)
 		return
 	}
 
 	// Rotate the refresh token
 	newRefreshToken, err := user.RotateRefreshToken(context.Request.Context(), request.RefreshToken)
 	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": "could not rotate refresh token",
-		})
+		middleware.AbortWithError(context, err)
 		return
 	}
 
@@ -170,18 +139,14 @@ func logout(context *gin.Context) {
 
 	err := context.ShouldBindJSON(&request)
 	if err != nil {
-		context.JSON(http.StatusBadRequest, gin.H{
-			"message": "refresh token required",
-		})
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "refresh_token_required", "refresh token required")
 		return
 	}
 
 	// Delete the refresh token from database
 	err = models.DeleteRefreshToken(context.Request.Context(), request.RefreshToken)
 	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": "could not logout",
-		})
+		middleware.AbortWithError(context, err)
 		return
 	}
 
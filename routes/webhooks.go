@@ -0,0 +1,213 @@
+package routes
+
+import (
+	"REST-API/middleware"
+	"REST-API/models"
+	"REST-API/utils"
+	"REST-API/webhooks"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sends the test deliveries, set by RegisterRoutes
+var webhookSender *webhooks.Sender
+
+type webhookRequest struct {
+	URL         string   `json:"url"`
+	Topics      []string `json:"topics"`
+	Description string   `json:"description"`
+	Active      *bool    `json:"active"` // left unchanged when omitted
+}
+
+// binds and validates the request into webhook, aborting on failure
+func bindWebhook(context *gin.Context, webhook *models.Webhook) bool {
+	var request webhookRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_body", "could not parse request data")
+		return false
+	}
+
+	webhook.URL = request.URL
+	webhook.Topics = request.Topics
+	webhook.Description = request.Description
+	if request.Active != nil {
+		webhook.Active = *request.Active
+	}
+
+	if validationErrors := utils.ValidateStruct(webhook); validationErrors != nil {
+		abortValidationFailed(context, "validation failed", validationErrors)
+		return false
+	}
+	return true
+}
+
+// loads the webhook from :id, allowing only its owner and admins
+func loadOwnedWebhook(context *gin.Context) (*models.Webhook, bool) {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		middleware.AbortWithProblem(context, http.StatusBadRequest, "invalid_webhook_id", "invalid webhook ID")
+		return nil, false
+	}
+
+	webhook, err := models.GetWebhookByID(context.Request.Context(), id)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return nil, false
+	}
+
+	if webhook.UserID != context.GetInt("userId") && context.GetString("role") != "admin" {
+		middleware.AbortWithProblem(context, http.StatusForbidden, "forbidden", "only the webhook owner can perform this action")
+		return nil, false
+	}
+
+	return webhook, true
+}
+
+// createWebhook handles POST /webhooks. The signing secret is only ever
+// returned here.
+func createWebhook(context *gin.Context) {
+	webhook := models.Webhook{UserID: context.GetInt("userId")}
+	if !bindWebhook(context, &webhook) {
+		return
+	}
+
+	if err := webhook.Save(context.Request.Context()); err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	recordAudit(context, models.AuditWebhookCreate, "webhook", webhook.ID, nil, webhook)
+
+	context.JSON(http.StatusCreated, gin.H{
+		"message": "webhook created",
+		"webhook": webhook,
+		"secret":  webhook.Secret,
+	})
+}
+
+// getWebhooks handles GET /webhooks, the current user's webhooks
+func getWebhooks(context *gin.Context) {
+	hooks, err := models.GetUserWebhooks(context.Request.Context(), context.GetInt("userId"))
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"webhooks": hooks,
+	})
+}
+
+// getWebhook handles GET /webhooks/:id
+func getWebhook(context *gin.Context) {
+	webhook, ok := loadOwnedWebhook(context)
+	if !ok {
+		return
+	}
+
+	context.JSON(http.StatusOK, webhook)
+}
+
+// updateWebhook handles PUT /webhooks/:id
+func updateWebhook(context *gin.Context) {
+	webhook, ok := loadOwnedWebhook(context)
+	if !ok {
+		return
+	}
+	before := *webhook
+	if !bindWebhook(context, webhook) {
+		return
+	}
+
+	if err := webhook.Update(context.Request.Context()); err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	recordAudit(context, models.AuditWebhookUpdate, "webhook", webhook.ID, before, webhook)
+
+	context.JSON(http.StatusOK, gin.H{
+		"message": "webhook updated",
+		"webhook": webhook,
+	})
+}
+
+// deleteWebhook handles DELETE /webhooks/:id
+func deleteWebhook(context *gin.Context) {
+	webhook, ok := loadOwnedWebhook(context)
+	if !ok {
+		return
+	}
+
+	if err := models.DeleteWebhook(context.Request.Context(), webhook.ID); err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	recordAudit(context, models.AuditWebhookDelete, "webhook", webhook.ID, webhook, nil)
+
+	context.JSON(http.StatusOK, gin.H{
+		"message": "webhook deleted",
+	})
+}
+
+// getWebhookDeliveries handles GET /webhooks/:id/deliveries, newest first
+func getWebhookDeliveries(context *gin.Context) {
+	webhook, ok := loadOwnedWebhook(context)
+	if !ok {
+		return
+	}
+	page, limit, ok := parsePagination(context)
+	if !ok {
+		return
+	}
+
+	deliveries, total, err := models.GetWebhookDeliveries(context.Request.Context(), webhook.ID, page, limit)
+	if err != nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, paginatedResponse(deliveries, total, page, limit))
+}
+
+// testWebhook handles POST /webhooks/:id/test, sending a webhook.test
+// message straight away and reporting how the endpoint responded. Test
+// deliveries aren't retried.
+func testWebhook(context *gin.Context) {
+	webhook, ok := loadOwnedWebhook(context)
+	if !ok {
+		return
+	}
+
+	now := time.Now().UTC()
+	data, _ := json.Marshal(gin.H{
+		"webhookId": webhook.ID,
+		"message":   "This is a test delivery.",
+	})
+	message := models.WebhookMessage{
+		ID:        "test-" + strconv.FormatInt(now.UnixNano(), 10),
+		Topic:     models.WebhookTopicTest,
+		CreatedAt: now,
+		Data:      data,
+	}
+
+	delivery, err := webhookSender.Send(context.Request.Context(), webhook, message, 1)
+	if delivery == nil {
+		middleware.AbortWithError(context, err)
+		return
+	}
+
+	result := "test delivery succeeded"
+	if err != nil {
+		result = "test delivery failed: " + err.Error()
+	}
+	context.JSON(http.StatusOK, gin.H{
+		"message":  result,
+		"delivery": delivery,
+	})
+}
@@ -0,0 +1,264 @@
+package scheduler
+
+import (
+	"REST-API/metrics"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrJobNotFound = errors.New("no job with that name")
+	ErrJobRunning  = errors.New("the job is already running")
+	ErrStopped     = errors.New("the scheduler has stopped")
+)
+
+// how many finished runs each job remembers
+const historySize = 20
+
+// how a run ended
+const (
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+	StatusSkipped = "skipped" // still running from the previous time it was due
+)
+
+type Run struct {
+	Trigger    string    `json:"trigger"` // schedule, startup or manual
+	Status     string    `json:"status"`
+	StartedAt  time.Time `json:"startedAt"`
+	DurationMs int64     `json:"durationMs"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// a job as reported by Jobs, runs are newest first
+type JobStatus struct {
+	Name     string    `json:"name"`
+	Schedule string    `json:"schedule"`
+	Running  bool      `json:"running"`
+	NextRun  time.Time `json:"nextRun"`
+	Runs     []Run     `json:"runs"`
+}
+
+type job struct {
+	name       string
+	spec       string
+	schedule   Schedule
+	run        func(context.Context) error
+	runOnStart bool
+	running    atomic.Bool
+
+	mu      sync.Mutex
+	nextRun time.Time
+	runs    []Run
+}
+
+// runs jobs in the background on their schedules. A job never runs twice at
+// once: if it is still running when it's next due, that run is recorded as
+// skipped. Stop cancels the context handed to running jobs and waits for them.
+type Scheduler struct {
+	mu      sync.Mutex
+	jobs    []*job
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+func New() *Scheduler {
+	return &Scheduler{}
+}
+
+// changes how Add registers a job
+type Option func(*job)
+
+// also runs the job as soon as the scheduler starts. Without it a job first
+// runs a whole interval after startup, so one on a long interval might never
+// run in a process that restarts more often than that.
+var RunOnStart Option = func(j *job) { j.runOnStart = true }
+
+// registers a job, call before Start
+func (s *Scheduler) Add(name, spec string, run func(context.Context) error, options ...Option) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.find(name) != nil {
+		return fmt.Errorf("job %s is already registered", name)
+	}
+	j := &job{name: name, spec: spec, schedule: schedule, run: run}
+	for _, option := range options {
+		option(j)
+	}
+	s.jobs = append(s.jobs, j)
+	metrics.JobRuns.WithLabelValues(name, StatusSuccess)
+	metrics.JobRuns.WithLabelValues(name, StatusFailure)
+	return nil
+}
+
+// starts every job's timer. Jobs run with a context derived from ctx.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.loop(j)
+		}()
+	}
+}
+
+// cancels running jobs and waits for them to return
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	s.stopped = true
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+// starts a job outside its schedule. It runs in the background, so it isn't
+// tied to the caller's context.
+func (s *Scheduler) RunNow(name string) error {
+	s.mu.Lock()
+	j := s.find(name)
+	s.mu.Unlock()
+	if j == nil {
+		return ErrJobNotFound
+	}
+	return s.start(j, "manual")
+}
+
+// every job in the order they were added
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mu.Lock()
+	jobs := slices.Clone(s.jobs)
+	s.mu.Unlock()
+
+	statuses := make([]JobStatus, 0, len(jobs))
+	for _, j := range jobs {
+		j.mu.Lock()
+		statuses = append(statuses, JobStatus{
+			Name:     j.name,
+			Schedule: j.spec,
+			Running:  j.running.Load(),
+			NextRun:  j.nextRun,
+			Runs:     append([]Run{}, j.runs...),
+		})
+		j.mu.Unlock()
+	}
+	return statuses
+}
+
+func (s *Scheduler) find(name string) *job {
+	for _, j := range s.jobs {
+		if j.name == name {
+			return j
+		}
+	}
+	return nil
+}
+
+func (s *Scheduler) loop(j *job) {
+	if j.runOnStart {
+		s.start(j, "startup")
+	}
+	for {
+		next := j.schedule.Next(time.Now())
+		if next.IsZero() {
+			slog.Warn("Job schedule never matches, it won't run", "job", j.name, "schedule", j.spec)
+			return
+		}
+		j.mu.Lock()
+		j.nextRun = next
+		j.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := s.start(j, "schedule"); errors.Is(err, ErrJobRunning) {
+			slog.Warn("Job still running from its last schedule, skipped", "job", j.name)
+		}
+	}
+}
+
+// runs j in its own goroutine unless it is already running
+func (s *Scheduler) start(j *job, trigger string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped || s.ctx == nil {
+		return ErrStopped
+	}
+
+	if !j.running.CompareAndSwap(false, true) {
+		j.record(Run{Trigger: trigger, Status: StatusSkipped, StartedAt: time.Now().UTC()})
+		metrics.JobRuns.WithLabelValues(j.name, StatusSkipped).Inc()
+		return ErrJobRunning
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer j.running.Store(false)
+		s.execute(j, trigger)
+	}()
+	return nil
+}
+
+func (s *Scheduler) execute(j *job, trigger string) {
+	run := Run{Trigger: trigger, StartedAt: time.Now().UTC()}
+	err := safeRun(s.ctx, j.run)
+	duration := time.Since(run.StartedAt)
+	run.DurationMs = duration.Milliseconds()
+
+	run.Status = StatusSuccess
+	if err != nil {
+		run.Status = StatusFailure
+		run.Error = err.Error()
+		slog.Error("Job failed", "job", j.name, "trigger", trigger, "duration_ms", run.DurationMs, "error", err)
+	} else {
+		slog.Debug("Job finished", "job", j.name, "trigger", trigger, "duration_ms", run.DurationMs)
+	}
+
+	j.record(run)
+	metrics.JobRuns.WithLabelValues(j.name, run.Status).Inc()
+	metrics.JobDuration.WithLabelValues(j.name).Observe(duration.Seconds())
+}
+
+// a panicking job fails its run rather than taking the server down
+func safeRun(ctx context.Context, run func(context.Context) error) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+	return run(ctx)
+}
+
+func (j *job) record(run Run) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.runs = append([]Run{run}, j.runs...)
+	if len(j.runs) > historySize {
+		j.runs = j.runs[:historySize]
+	}
+}
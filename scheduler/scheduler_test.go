@@ -0,0 +1,137 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunNow_PreventsOverlap(t *testing.T) {
+	jobs := New()
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	jobs.Add("slow", "@every 1h", func(ctx context.Context) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	})
+	jobs.Start(context.Background())
+	defer jobs.Stop()
+
+	if err := jobs.RunNow("slow"); err != nil {
+		t.Fatalf("expected the job to start, got: %v", err)
+	}
+	<-started
+	if err := jobs.RunNow("slow"); !errors.Is(err, ErrJobRunning) {
+		t.Errorf("expected a second run to be refused while the first is running, got: %v", err)
+	}
+	if err := jobs.RunNow("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("expected an unknown job to be reported, got: %v", err)
+	}
+	close(release)
+
+	runs := waitForRuns(t, jobs, 2)
+	if runs[0].Status != StatusSuccess || runs[1].Status != StatusSkipped {
+		t.Errorf("expected a success after a skipped run, newest first, got %+v", runs)
+	}
+}
+
+func TestScheduler_RecordsFailuresAndPanics(t *testing.T) {
+	jobs := New()
+	jobs.Add("broken", "@every 1h", func(ctx context.Context) error {
+		return errors.New("disk full")
+	})
+	jobs.Add("panics", "@every 1h", func(ctx context.Context) error {
+		panic("boom")
+	})
+	jobs.Start(context.Background())
+	defer jobs.Stop()
+
+	jobs.RunNow("broken")
+	jobs.RunNow("panics")
+	waitForRuns(t, jobs, 1)
+
+	for _, job := range jobs.Jobs() {
+		if len(job.Runs) != 1 || job.Runs[0].Status != StatusFailure || job.Runs[0].Error == "" {
+			t.Errorf("%s: expected one failed run with its error, got %+v", job.Name, job.Runs)
+		}
+		if job.NextRun.IsZero() {
+			t.Errorf("%s: expected the next scheduled run to be reported", job.Name)
+		}
+	}
+}
+
+func TestStop_CancelsRunningJobs(t *testing.T) {
+	jobs := New()
+	started := make(chan struct{})
+	jobs.Add("waits", "@every 1h", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	jobs.Start(context.Background())
+	jobs.RunNow("waits")
+	<-started
+
+	stopped := make(chan struct{})
+	go func() {
+		jobs.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("expected Stop to cancel the running job and return")
+	}
+
+	if err := jobs.RunNow("waits"); !errors.Is(err, ErrStopped) {
+		t.Errorf("expected no runs after Stop, got: %v", err)
+	}
+}
+
+func TestAdd_RejectsInvalidSchedule(t *testing.T) {
+	if err := New().Add("bad", "every hour", func(context.Context) error { return nil }); err == nil {
+		t.Error("expected an invalid schedule to be rejected")
+	}
+}
+
+// waits until the first job has recorded at least n finished runs
+func waitForRuns(t *testing.T, jobs *Scheduler, n int) []Run {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		done := true
+		for _, job := range jobs.Jobs() {
+			if len(job.Runs) < n || job.Running {
+				done = false
+			}
+		}
+		if done {
+			return jobs.Jobs()[0].Runs
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d runs", n)
+	return nil
+}
+
+func TestRunOnStart(t *testing.T) {
+	jobs := New()
+	jobs.Add("eager", "@every 1h", func(ctx context.Context) error { return nil }, RunOnStart)
+	jobs.Add("lazy", "@every 1h", func(ctx context.Context) error { return nil })
+	jobs.Start(context.Background())
+	defer jobs.Stop()
+
+	var runs []Run
+	for deadline := time.Now().Add(time.Second); len(runs) == 0 && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+		runs = jobs.Jobs()[0].Runs
+	}
+	if len(runs) != 1 || runs[0].Trigger != "startup" {
+		t.Errorf("expected one startup run, got %+v", runs)
+	}
+	if lazy := jobs.Jobs()[1]; len(lazy.Runs) != 0 {
+		t.Errorf("expected the lazy job to wait for its schedule, got %+v", lazy.Runs)
+	}
+}
@@ -0,0 +1,44 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+)
+
+func TestSetup_FileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.json")
+
+	shutdown, err := Setup(context.Background(), Options{Exporter: "file", File: path, SampleRatio: 1})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	_, span := otel.Tracer("test").Start(context.Background(), "test-span")
+	span.End()
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("expected no error on shutdown, got: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read trace file: %v", err)
+	}
+	if !strings.Contains(string(data), "test-span") || !strings.Contains(string(data), ServiceName) {
+		t.Errorf("expected the span to be exported, got %q", data)
+	}
+}
+
+func TestSetup_InvalidOptions(t *testing.T) {
+	if _, err := Setup(context.Background(), Options{Exporter: "zipkin"}); err == nil {
+		t.Error("expected an error for an unknown exporter")
+	}
+	if _, err := Setup(context.Background(), Options{Exporter: "file"}); err == nil {
+		t.Error("expected an error for the file exporter without a file")
+	}
+}
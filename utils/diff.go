@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// a single field's value before and after a change
+type FieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// compares the JSON forms of before and after field by field and returns
+// only the fields that changed. Either side may be nil, so creates and
+// deletes show every field going from or to null.
+func Diff(before, after any) (map[string]FieldChange, error) {
+	beforeFields, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]FieldChange)
+	for field, from := range beforeFields {
+		to := afterFields[field]
+		if !reflect.DeepEqual(from, to) {
+			changes[field] = FieldChange{From: from, To: to}
+		}
+	}
+	for field, to := range afterFields {
+		if _, ok := beforeFields[field]; !ok {
+			changes[field] = FieldChange{From: nil, To: to}
+		}
+	}
+
+	return changes, nil
+}
+
+func jsonFields(value any) (map[string]any, error) {
+	fields := make(map[string]any)
+	if value == nil || reflect.ValueOf(value).Kind() == reflect.Pointer && reflect.ValueOf(value).IsNil() {
+		return fields, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"testing"
+)
+
+type diffSubject struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+	Note  string `json:"note,omitempty"`
+}
+
+func TestDiff(t *testing.T) {
+	changes, err := Diff(diffSubject{Name: "a", Count: 1}, diffSubject{Name: "a", Count: 2, Note: "new"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changed fields, got %d: %v", len(changes), changes)
+	}
+	if changes["count"].From != float64(1) || changes["count"].To != float64(2) {
+		t.Errorf("expected count to change from 1 to 2, got %v", changes["count"])
+	}
+	if changes["note"].From != nil || changes["note"].To != "new" {
+		t.Errorf("expected note to be added, got %v", changes["note"])
+	}
+	if _, ok := changes["name"]; ok {
+		t.Error("expected unchanged name to be left out")
+	}
+}
+
+func TestDiff_NilSide(t *testing.T) {
+	var missing *diffSubject
+	changes, err := Diff(missing, diffSubject{Name: "a"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if changes["name"].From != nil || changes["name"].To != "a" {
+		t.Errorf("expected name to go from null to a, got %v", changes["name"])
+	}
+}
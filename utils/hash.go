@@ -18,3 +18,12 @@ func CheckPasswordHash(password, hashedPassword string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 	return err == nil
 }
+
+// creates a random password for accounts set up from the command line
+func GeneratePassword() (string, error) {
+	password, err := randomHex(12)
+	if err != nil {
+		return "", errors.New("could not generate password")
+	}
+	return password, nil
+}
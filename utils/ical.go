@@ -0,0 +1,239 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// iCalendar (RFC 5545) timestamps are always written in UTC
+const icalTimeFormat = "20060102T150405Z"
+
+// lines longer than 75 octets must be folded
+const icalMaxLineLength = 75
+
+type CalendarEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	End         time.Time
+	URL         string
+	Status      string // CONFIRMED, TENTATIVE or CANCELLED
+}
+
+// renders a VCALENDAR document containing one VEVENT per event
+func BuildICalendar(name string, events []CalendarEvent) string {
+	var b strings.Builder
+	stamp := time.Now().UTC().Format(icalTimeFormat)
+
+	writeICalLine(&b, "BEGIN:VCALENDAR")
+	writeICalLine(&b, "VERSION:2.0")
+	writeICalLine(&b, "PRODID:-//Events REST API//EN")
+	writeICalLine(&b, "CALSCALE:GREGORIAN")
+	writeICalLine(&b, "METHOD:PUBLISH")
+	if name != "" {
+		writeICalLine(&b, "X-WR-CALNAME:"+escapeICalText(name))
+	}
+
+	for _, event := range events {
+		writeICalLine(&b, "BEGIN:VEVENT")
+		writeICalLine(&b, "UID:"+event.UID)
+		writeICalLine(&b, "DTSTAMP:"+stamp)
+		writeICalLine(&b, "DTSTART:"+event.Start.UTC().Format(icalTimeFormat))
+		if !event.End.IsZero() {
+			writeICalLine(&b, "DTEND:"+event.End.UTC().Format(icalTimeFormat))
+		}
+		writeICalLine(&b, "SUMMARY:"+escapeICalText(event.Summary))
+		if event.Description != "" {
+			writeICalLine(&b, "DESCRIPTION:"+escapeICalText(event.Description))
+		}
+		if event.Location != "" {
+			writeICalLine(&b, "LOCATION:"+escapeICalText(event.Location))
+		}
+		if event.URL != "" {
+			writeICalLine(&b, "URL:"+event.URL)
+		}
+		if event.Status != "" {
+			writeICalLine(&b, "STATUS:"+event.Status)
+		}
+		writeICalLine(&b, "END:VEVENT")
+	}
+
+	writeICalLine(&b, "END:VCALENDAR")
+	return b.String()
+}
+
+// escapes characters that have special meaning in TEXT values
+func escapeICalText(value string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	)
+	return replacer.Replace(value)
+}
+
+// writes a content line terminated by CRLF, folding it if needed
+func writeICalLine(b *strings.Builder, line string) {
+	limit := icalMaxLineLength
+	for len(line) > limit {
+		cut := limit
+		// never split a multi-byte UTF-8 character
+		for cut > 0 && !isUTF8Start(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// continuation lines start with a space, which counts towards the limit
+		limit = icalMaxLineLength - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+func isUTF8Start(c byte) bool {
+	return c&0xC0 != 0x80
+}
+
+// a VEVENT read from an uploaded calendar, Err is set when one of
+// its fields could not be parsed so callers can report it per event
+type ParsedCalendarEvent struct {
+	CalendarEvent
+	Err error
+}
+
+// parses every VEVENT in an iCalendar document
+func ParseICalendar(r io.Reader) ([]ParsedCalendarEvent, error) {
+	lines, err := unfoldICalLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []ParsedCalendarEvent
+	var current *ParsedCalendarEvent
+	for _, line := range lines {
+		name, params, value, ok := splitICalLine(line)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			current = &ParsedCalendarEvent{}
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if current != nil {
+				events = append(events, *current)
+			}
+			current = nil
+		case current == nil:
+			continue
+		case name == "UID":
+			current.UID = value
+		case name == "SUMMARY":
+			current.Summary = unescapeICalText(value)
+		case name == "DESCRIPTION":
+			current.Description = unescapeICalText(value)
+		case name == "LOCATION":
+			current.Location = unescapeICalText(value)
+		case name == "URL":
+			current.URL = value
+		case name == "STATUS":
+			current.Status = strings.ToUpper(value)
+		case name == "DTSTART" || name == "DTEND":
+			parsed, parseErr := parseICalTime(value, params)
+			if parseErr != nil {
+				if current.Err == nil {
+					current.Err = fmt.Errorf("invalid %s: %w", strings.ToLower(name), parseErr)
+				}
+				continue
+			}
+			if name == "DTSTART" {
+				current.Start = parsed
+			} else {
+				current.End = parsed
+			}
+		}
+	}
+
+	if len(events) == 0 {
+		return nil, errors.New("no events found in calendar")
+	}
+	return events, nil
+}
+
+// joins folded continuation lines back into single content lines
+func unfoldICalLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.New("could not read calendar")
+	}
+	return lines, nil
+}
+
+// splits "NAME;PARAM=x:value" into its parts
+func splitICalLine(line string) (name string, params map[string]string, value string, ok bool) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return "", nil, "", false
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	name = strings.ToUpper(parts[0])
+	params = make(map[string]string)
+	for _, param := range parts[1:] {
+		if key, val, found := strings.Cut(param, "="); found {
+			params[strings.ToUpper(key)] = strings.Trim(val, `"`)
+		}
+	}
+	return name, params, line[colon+1:], true
+}
+
+func parseICalTime(value string, params map[string]string) (time.Time, error) {
+	if params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		return time.ParseInLocation("20060102", value, time.UTC)
+	}
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse(icalTimeFormat, value)
+	}
+
+	// floating times without a TZID are treated as UTC
+	location := time.UTC
+	if tzid := params["TZID"]; tzid != "" {
+		loaded, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unknown time zone %q", tzid)
+		}
+		location = loaded
+	}
+	return time.ParseInLocation("20060102T150405", value, location)
+}
+
+func unescapeICalText(value string) string {
+	replacer := strings.NewReplacer(
+		`\\`, `\`,
+		`\;`, ";",
+		`\,`, ",",
+		`\n`, "\n",
+		`\N`, "\n",
+	)
+	return replacer.Replace(value)
+}
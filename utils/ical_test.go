@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildICalendar(t *testing.T) {
+	start := time.Date(2030, 1, 2, 15, 4, 5, 0, time.UTC)
+	calendar := BuildICalendar("Test", []CalendarEvent{{
+		UID:      "event-1@example.com",
+		Summary:  "Meetup, Go; Gophers",
+		Location: "Room 1",
+		Start:    start,
+		End:      start.Add(time.Hour),
+	}})
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"UID:event-1@example.com\r\n",
+		"DTSTART:20300102T150405Z\r\n",
+		"DTEND:20300102T160405Z\r\n",
+		"SUMMARY:Meetup\\, Go\\; Gophers\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(calendar, want) {
+			t.Errorf("expected calendar to contain %q, got:\n%s", want, calendar)
+		}
+	}
+}
+
+func TestBuildICalendar_FoldsLongLines(t *testing.T) {
+	calendar := BuildICalendar("", []CalendarEvent{{
+		UID:         "event-1@example.com",
+		Summary:     "Long",
+		Description: strings.Repeat("é", 100),
+		Start:       time.Now(),
+	}})
+
+	for _, line := range strings.Split(calendar, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("expected lines of at most 75 octets, got %d: %q", len(line), line)
+		}
+	}
+}
+
+func TestParseICalendar(t *testing.T) {
+	input := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"SUMMARY:Meetup\\, Go\r\n" +
+		"DESCRIPTION:A long description that was\r\n  folded across lines\r\n" +
+		"DTSTART;TZID=Europe/Berlin:20300102T150000\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"SUMMARY:Broken\r\n" +
+		"DTSTART:not-a-date\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	events, err := ParseICalendar(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+
+	first := events[0]
+	if first.Err != nil {
+		t.Errorf("expected first event to parse, got: %v", first.Err)
+	}
+	if first.Summary != "Meetup, Go" {
+		t.Errorf("expected unescaped summary, got %q", first.Summary)
+	}
+	if first.Description != "A long description that was folded across lines" {
+		t.Errorf("expected unfolded description, got %q", first.Description)
+	}
+	if want := time.Date(2030, 1, 2, 14, 0, 0, 0, time.UTC); !first.Start.Equal(want) {
+		t.Errorf("expected start %v, got %v", want, first.Start.UTC())
+	}
+
+	if events[1].Err == nil {
+		t.Error("expected an error for the event with an invalid DTSTART")
+	}
+}
@@ -17,10 +17,10 @@ func GenerateToken(email string, userID int, role string) (string, error) {
 		"email":  email,
 		"userId": userID,
 		"role":   role,
-		"exp":    time.Now().Add(config.App.AccessTokenExpiry).Unix(),
+		"exp":    time.Now().Add(config.Get().AccessTokenExpiry).Unix(),
 	})
 
-	tokenString, err := token.SignedString([]byte(config.App.JWTSecret))
+	tokenString, err := token.SignedString([]byte(config.Get().JWTSecret))
 	if err != nil {
 		return "", errors.New("could not generate token")
 	}
@@ -34,7 +34,7 @@ func VerifyToken(tokenString string) (int, string, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unexpected signing method")
 		}
-		return []byte(config.App.JWTSecret), nil
+		return []byte(config.Get().JWTSecret), nil
 	})
 
 	if err != nil {
@@ -60,12 +60,37 @@ func VerifyToken(tokenString string) (int, string, error) {
 
 // creates a cryptographically secure random token
 func GenerateRefreshToken() (string, error) {
-	// create a 32-byte random token
-	bytes := make([]byte, 32)
-	_, err := rand.Read(bytes) // fills the byte slice with random data
+	token, err := randomHex(32)
 	if err != nil {
 		return "", errors.New("could not generate refresh token")
 	}
+	return token, nil
+}
+
+// creates the secret token embedded in a user's calendar feed URL
+func GenerateCalendarToken() (string, error) {
+	token, err := randomHex(24)
+	if err != nil {
+		return "", errors.New("could not generate calendar token")
+	}
+	return token, nil
+}
+
+// creates the secret a webhook's deliveries are signed with
+func GenerateWebhookSecret() (string, error) {
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", errors.New("could not generate webhook secret")
+	}
+	return "whsec_" + secret, nil
+}
+
+func randomHex(size int) (string, error) {
+	bytes := make([]byte, size)
+	_, err := rand.Read(bytes) // fills the byte slice with random data
+	if err != nil {
+		return "", err
+	}
 
 	return hex.EncodeToString(bytes), nil
 }
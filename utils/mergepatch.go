@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// applies an RFC 7396 JSON Merge Patch to target: objects are merged
+// recursively, null removes a member, and anything else replaces it
+func MergePatch(target, patch []byte) ([]byte, error) {
+	var targetValue, patchValue any
+	if err := json.Unmarshal(target, &targetValue); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, errors.New("invalid merge patch: " + err.Error())
+	}
+
+	return json.Marshal(mergeValue(targetValue, patchValue))
+}
+
+func mergeValue(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = make(map[string]any)
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergeValue(targetObject[key], value)
+	}
+	return targetObject
+}
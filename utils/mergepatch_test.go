@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	// examples from RFC 7396 appendix A
+	cases := []struct {
+		target, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, c := range cases {
+		got, err := MergePatch([]byte(c.target), []byte(c.patch))
+		if err != nil {
+			t.Errorf("MergePatch(%s, %s): unexpected error: %v", c.target, c.patch, err)
+			continue
+		}
+
+		var gotValue, wantValue any
+		json.Unmarshal(got, &gotValue)
+		json.Unmarshal([]byte(c.want), &wantValue)
+		if !reflect.DeepEqual(gotValue, wantValue) {
+			t.Errorf("MergePatch(%s, %s) = %s, want %s", c.target, c.patch, got, c.want)
+		}
+	}
+}
+
+func TestMergePatch_InvalidPatch(t *testing.T) {
+	if _, err := MergePatch([]byte(`{}`), []byte(`{not json`)); err == nil {
+		t.Error("expected error for an invalid patch, got nil")
+	}
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 	"time"
 
@@ -17,9 +18,18 @@ type ValidationError struct {
 }
 
 func ValidateStruct(s any) []ValidationError {
+	return toValidationErrors(validate.Struct(s))
+}
+
+// validates only the named struct fields, for partial updates that
+// shouldn't re-check fields the client didn't touch
+func ValidateStructPartial(s any, fields ...string) []ValidationError {
+	return toValidationErrors(validate.StructPartial(s, fields...))
+}
+
+func toValidationErrors(err error) []ValidationError {
 	var validationErrors []ValidationError
 
-	err := validate.Struct(s)
 	if err != nil {
 		for _, err := range err.(validator.ValidationErrors) {
 			var ve ValidationError
@@ -39,11 +49,20 @@ func formatValidationError(err validator.FieldError) string {
 	case "email":
 		return "invalid email format"
 	case "min":
+		if err.Kind() == reflect.Slice {
+			return fmt.Sprintf("%s must have at least %s item(s)", strings.ToLower(err.Field()), err.Param())
+		}
 		return fmt.Sprintf("%s must be at least %s characters", strings.ToLower(err.Field()), err.Param())
 	case "max":
 		return fmt.Sprintf("%s must be at most %s characters", strings.ToLower(err.Field()), err.Param())
 	case "future_date":
 		return "dateTime must be in the future"
+	case "http_url":
+		return fmt.Sprintf("%s must be an http or https URL", strings.ToLower(err.Field()))
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", strings.ToLower(err.Field()), strings.ReplaceAll(err.Param(), " ", ", "))
+	case "gtfield":
+		return fmt.Sprintf("%s must be after %s", strings.ToLower(err.Field()), strings.ToLower(err.Param()))
 	default:
 		return fmt.Sprintf("%s is invalid", strings.ToLower(err.Field()))
 	}
@@ -0,0 +1,43 @@
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// set at build time, e.g.
+//
+//	go build -ldflags "-X REST-API/version.Version=v1.2.0 -X REST-API/version.Commit=$(git rev-parse HEAD) -X REST-API/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"buildTime"`
+	GoVersion string `json:"goVersion"`
+}
+
+// returns the injected build metadata, falling back to the VCS revision the
+// go toolchain embeds when the commit wasn't set
+func Get() BuildInfo {
+	info := BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	if buildInfo, ok := debug.ReadBuildInfo(); ok && info.Commit == "" {
+		for _, setting := range buildInfo.Settings {
+			if setting.Key == "vcs.revision" {
+				info.Commit = setting.Value
+			}
+		}
+	}
+
+	return info
+}
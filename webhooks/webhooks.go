@@ -0,0 +1,245 @@
+package webhooks
+
+import (
+	"REST-API/models"
+	"REST-API/outbox"
+	"REST-API/version"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	// the WebhookMessage ID, the same across retries so receivers can deduplicate
+	IDHeader    = "X-Webhook-Id"
+	TopicHeader = "X-Webhook-Topic"
+)
+
+// how much of a receiver's response is read, so the connection can be reused
+const maxResponseBody = 1024
+
+var ErrPrivateTarget = errors.New("webhook target is a loopback, private or reserved address")
+
+// delivers messages to webhook endpoints and logs every attempt
+type Sender struct {
+	client *http.Client
+}
+
+// timeout bounds each delivery, including reading the response. Unless
+// allowLocal is set, endpoints that resolve to loopback, private, link-local
+// or other non-public addresses are refused, so webhooks can't be used to
+// reach internal services.
+func NewSender(timeout time.Duration, allowLocal bool) *Sender {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowLocal {
+		// checked on the address actually dialed, so a hostname that
+		// resolves differently later can't get around it
+		dialer.Control = refusePrivateTargets
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// a proxy would be dialed instead of the endpoint, skipping the check
+	transport.Proxy = nil
+
+	return &Sender{client: &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		// a redirect is reported as the response, never followed with the signed body
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// the IANA special-purpose ranges a webhook may not point into: anything
+// not globally reachable, along with documentation, benchmarking and
+// translation ranges that could lead somewhere internal
+var deniedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // this network
+	netip.MustParsePrefix("10.0.0.0/8"),      // private
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT
+	netip.MustParsePrefix("127.0.0.0/8"),     // loopback
+	netip.MustParsePrefix("169.254.0.0/16"),  // link-local, including cloud metadata
+	netip.MustParsePrefix("172.16.0.0/12"),   // private
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("192.31.196.0/24"), // AS112
+	netip.MustParsePrefix("192.52.193.0/24"), // AMT
+	netip.MustParsePrefix("192.88.99.0/24"),  // 6to4 relay anycast
+	netip.MustParsePrefix("192.168.0.0/16"),  // private
+	netip.MustParsePrefix("192.175.48.0/24"), // direct delegation AS112
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("224.0.0.0/4"),     // multicast
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved and broadcast
+
+	netip.MustParsePrefix("::/96"),           // unspecified, loopback and IPv4-compatible
+	netip.MustParsePrefix("::ffff:0:0/96"),   // IPv4-mapped
+	netip.MustParsePrefix("::ffff:0:0:0/96"), // IPv4-translated
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // local-use NAT64
+	netip.MustParsePrefix("100::/64"),        // discard-only
+	netip.MustParsePrefix("2001::/23"),       // IETF protocol assignments, including Teredo
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+	netip.MustParsePrefix("2002::/16"),       // 6to4
+	netip.MustParsePrefix("3fff::/20"),       // documentation
+	netip.MustParsePrefix("5f00::/16"),       // segment routing
+	netip.MustParsePrefix("fc00::/7"),        // unique local
+	netip.MustParsePrefix("fe80::/10"),       // link-local
+	netip.MustParsePrefix("fec0::/10"),       // site-local
+	netip.MustParsePrefix("ff00::/8"),        // multicast
+}
+
+// the well-known NAT64 prefix, whose last 32 bits are the IPv4 address reached
+var nat64Prefix = netip.MustParsePrefix("64:ff9b::/96")
+
+func refusePrivateTargets(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	// a prefix never contains an address with a zone
+	ip = ip.WithZone("").Unmap()
+	if nat64Prefix.Contains(ip) {
+		embedded := ip.As16()
+		ip = netip.AddrFrom4([4]byte(embedded[12:]))
+	}
+	for _, prefix := range deniedPrefixes {
+		if prefix.Contains(ip) {
+			return ErrPrivateTarget
+		}
+	}
+	return nil
+}
+
+// POSTs the signed message to the webhook and records the attempt in its
+// delivery log. Anything but a 2xx response is returned as an error.
+func (s *Sender) Send(ctx context.Context, webhook *models.Webhook, message models.WebhookMessage, attempt int) (*models.WebhookDelivery, error) {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := &models.WebhookDelivery{
+		WebhookID:   webhook.ID,
+		MessageID:   message.ID,
+		Topic:       message.Topic,
+		Attempt:     attempt,
+		RequestBody: body,
+	}
+
+	start := time.Now()
+	sendErr := s.post(ctx, webhook, message, body, delivery)
+	delivery.DurationMs = time.Since(start).Milliseconds()
+	if sendErr != nil {
+		delivery.Error = sendErr.Error()
+	}
+
+	// the log is written even when the request's context has run out
+	if err := models.RecordWebhookDelivery(context.WithoutCancel(ctx), delivery); err != nil {
+		slog.Error("Could not record webhook delivery", "webhook_id", webhook.ID, "error", err)
+	}
+	return delivery, sendErr
+}
+
+func (s *Sender) post(ctx context.Context, webhook *models.Webhook, message models.WebhookMessage, body []byte, delivery *models.WebhookDelivery) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "events-api-webhooks/"+version.Version)
+	request.Header.Set(IDHeader, message.ID)
+	request.Header.Set(TopicHeader, message.Topic)
+	request.Header.Set(SignatureHeader, Sign(webhook.Secret, time.Now(), body))
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	// the body isn't kept, a webhook must not be a way to read what's behind its URL
+	io.Copy(io.Discard, io.LimitReader(response.Body, maxResponseBody))
+	delivery.StatusCode = response.StatusCode
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("endpoint responded with %d", response.StatusCode)
+	}
+	return nil
+}
+
+// subscribes webhooks to the outbox: each change is fanned out into one
+// delivery per subscribed webhook, and each delivery is retried on its own
+// so one failing endpoint doesn't hold up the others
+func Register(dispatcher *outbox.Dispatcher, sender *Sender) {
+	for _, topic := range models.WebhookTopics {
+		dispatcher.Handle(topic, fanOut)
+	}
+	dispatcher.Handle(models.OutboxWebhookDelivery, sender.deliver)
+}
+
+func fanOut(ctx context.Context, message models.OutboxMessage) error {
+	eventID, err := eventIDOf(message)
+	if err != nil {
+		return err
+	}
+
+	webhookMessage := models.WebhookMessage{
+		ID:        strconv.Itoa(message.ID),
+		Topic:     message.Topic,
+		CreatedAt: message.CreatedAt,
+		Data:      message.Payload,
+	}
+	_, err = models.QueueWebhookDeliveries(ctx, webhookMessage, eventID)
+	return err
+}
+
+// events carry their own ID, registrations the ID of their event
+func eventIDOf(message models.OutboxMessage) (int, error) {
+	var payload struct {
+		ID      int `json:"id"`
+		EventID int `json:"eventId"`
+	}
+	if err := json.Unmarshal(message.Payload, &payload); err != nil {
+		return 0, fmt.Errorf("could not read %s payload: %w", message.Topic, err)
+	}
+	if payload.EventID != 0 {
+		return payload.EventID, nil
+	}
+	return payload.ID, nil
+}
+
+func (s *Sender) deliver(ctx context.Context, message models.OutboxMessage) error {
+	var job models.WebhookDeliveryJob
+	if err := json.Unmarshal(message.Payload, &job); err != nil {
+		return fmt.Errorf("could not read webhook delivery: %w", err)
+	}
+
+	webhook, err := models.GetWebhookByID(ctx, job.WebhookID)
+	// deleted or switched off since the delivery was queued
+	if errors.Is(err, models.ErrWebhookNotFound) || (err == nil && !webhook.Active) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = s.Send(ctx, webhook, job.Message, message.Attempts)
+	return err
+}
@@ -0,0 +1,183 @@
+package webhooks
+
+import (
+	"REST-API/config"
+	"REST-API/db"
+	"REST-API/models"
+	"REST-API/outbox"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	t.Setenv("JWT_SECRET", "test-secret-key")
+	t.Setenv("DB_PATH", ":memory:")
+	if err := config.Load(nil); err != nil {
+		t.Fatalf("could not load config: %v", err)
+	}
+	db.InitDB()
+	t.Cleanup(func() { db.Close() })
+
+	_, err := db.DB.Exec(`INSERT INTO users(id, email, password) VALUES (1, 'owner@example.com', 'hashed')`)
+	if err != nil {
+		t.Fatalf("could not seed test user: %v", err)
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	now := time.Unix(1700000000, 0)
+	header := Sign("whsec_test", now, body)
+
+	if err := Verify("whsec_test", header, body, 5*time.Minute, now.Add(time.Minute)); err != nil {
+		t.Errorf("expected a valid signature, got: %v", err)
+	}
+	if err := Verify("whsec_other", header, body, 5*time.Minute, now); err != ErrInvalidSignature {
+		t.Errorf("expected ErrInvalidSignature for the wrong secret, got: %v", err)
+	}
+	if err := Verify("whsec_test", header, []byte(`{"id":"2"}`), 5*time.Minute, now); err != ErrInvalidSignature {
+		t.Errorf("expected ErrInvalidSignature for a changed body, got: %v", err)
+	}
+	if err := Verify("whsec_test", header, body, 5*time.Minute, now.Add(time.Hour)); err != ErrSignatureExpired {
+		t.Errorf("expected ErrSignatureExpired for a replayed delivery, got: %v", err)
+	}
+	if err := Verify("whsec_test", "garbage", body, 5*time.Minute, now); err != ErrInvalidSignature {
+		t.Errorf("expected ErrInvalidSignature for a malformed header, got: %v", err)
+	}
+}
+
+func TestDelivery_SignedAndRetried(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var received []models.WebhookMessage
+	var webhook models.Webhook
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := Verify(webhook.Secret, r.Header.Get(SignatureHeader), body, time.Minute, time.Now()); err != nil {
+			t.Errorf("expected a valid signature, got: %v", err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		var message models.WebhookMessage
+		json.Unmarshal(body, &message)
+		received = append(received, message)
+		// the first attempt fails so the delivery is retried
+		if len(received) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer receiver.Close()
+
+	webhook = models.Webhook{UserID: 1, URL: receiver.URL, Topics: []string{models.OutboxEventCreated}}
+	if err := webhook.Save(ctx); err != nil {
+		t.Fatalf("could not save webhook: %v", err)
+	}
+	event := models.Event{
+		Name:        "Webhook Event",
+		Description: "An event webhooks hear about",
+		Location:    "Somewhere",
+		DateTime:    time.Now().Add(24 * time.Hour),
+		UserID:      1,
+		Status:      models.EventStatusPublished,
+	}
+	if err := event.Save(ctx); err != nil {
+		t.Fatalf("could not save event: %v", err)
+	}
+
+	dispatcher := outbox.New(outbox.Options{
+		Workers:      2,
+		PollInterval: 5 * time.Millisecond,
+		Lease:        time.Minute,
+		MaxAttempts:  3,
+		BackoffBase:  time.Millisecond,
+		BackoffMax:   2 * time.Millisecond,
+	})
+	Register(dispatcher, NewSender(time.Second, true))
+	dispatcher.Start(ctx)
+	defer dispatcher.Stop()
+
+	var deliveries []models.WebhookDelivery
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) && len(deliveries) < 2 {
+		time.Sleep(5 * time.Millisecond)
+		deliveries, _, _ = models.GetWebhookDeliveries(ctx, webhook.ID, 1, 10)
+	}
+	if len(deliveries) != 2 {
+		t.Fatalf("expected a failed and a successful attempt, got %+v", deliveries)
+	}
+	if deliveries[1].Succeeded || deliveries[1].StatusCode != 500 || !deliveries[0].Succeeded || deliveries[0].Attempt != 2 {
+		t.Errorf("expected attempt 1 to fail with 500 and attempt 2 to succeed, got %+v", deliveries)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if received[0].ID != received[1].ID || received[0].Topic != models.OutboxEventCreated {
+		t.Errorf("expected both attempts to carry the same event.created message, got %+v", received)
+	}
+	var data models.Event
+	json.Unmarshal(received[1].Data, &data)
+	if data.ID != event.ID {
+		t.Errorf("expected the event as data, got %s", received[1].Data)
+	}
+}
+
+func TestSend_RefusesPrivateTargets(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+
+	var called bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer receiver.Close()
+
+	webhook := models.Webhook{UserID: 1, URL: receiver.URL, Topics: []string{models.OutboxEventCreated}}
+	if err := webhook.Save(ctx); err != nil {
+		t.Fatalf("could not save webhook: %v", err)
+	}
+
+	message := models.WebhookMessage{ID: "test", Topic: models.WebhookTopicTest, Data: []byte("{}")}
+	delivery, err := NewSender(time.Second, false).Send(ctx, &webhook, message, 1)
+	if !errors.Is(err, ErrPrivateTarget) || called {
+		t.Fatalf("expected the loopback receiver to be refused, got %v", err)
+	}
+	if delivery.Succeeded || delivery.StatusCode != 0 {
+		t.Errorf("expected a failed delivery without a response, got %+v", delivery)
+	}
+
+	refused := []string{
+		"10.0.0.1:80", "169.254.169.254:80", "[::1]:443", "[::ffff:192.168.1.1]:80", "0.0.0.0:80", "224.0.0.1:80",
+		"0.1.2.3:80",                  // this network
+		"100.64.0.1:80",               // carrier-grade NAT
+		"[::ffff:100.100.100.200]:80", // carrier-grade NAT, IPv4-mapped
+		"198.18.0.1:80",               // benchmarking
+		"255.255.255.255:80",          // broadcast
+		"[64:ff9b::a00:1]:80",         // NAT64 to 10.0.0.1
+		"[64:ff9b::a9fe:a9fe]:80",     // NAT64 to 169.254.169.254
+		"[64:ff9b:1::1]:80",           // local-use NAT64
+		"[2002:a00:1::1]:80",          // 6to4 of 10.0.0.1
+		"[fd00::1]:80",                // unique local
+		"[fe80::1%eth0]:80",           // link-local with a zone
+	}
+	for _, address := range refused {
+		if err := refusePrivateTargets("tcp", address, nil); err != ErrPrivateTarget {
+			t.Errorf("expected %s to be refused, got %v", address, err)
+		}
+	}
+	for _, address := range []string{"93.184.216.34:443", "[2606:4700:4700::1111]:443", "[64:ff9b::5db8:d822]:443"} {
+		if err := refusePrivateTargets("tcp", address, nil); err != nil {
+			t.Errorf("expected public address %s to be allowed, got %v", address, err)
+		}
+	}
+}